@@ -0,0 +1,451 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/connector"
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+	"github.com/myopenfactory/edi-connector/v2/log"
+	"github.com/myopenfactory/edi-connector/v2/platform"
+	"github.com/myopenfactory/edi-connector/v2/version"
+)
+
+// command holds the flags shared by the subcommands working against the platform.
+type command struct {
+	flags      *flag.FlagSet
+	configFile string
+	logLevel   string
+	configId   string
+	authName   string
+	json       bool
+	// in and out are the input and output of the command, default to stdin and stdout
+	in  io.Reader
+	out io.Writer
+}
+
+func newCommand(name, configFile, logLevel string) *command {
+	cmd := &command{
+		flags:    flag.NewFlagSet(name, flag.ContinueOnError),
+		logLevel: logLevel,
+		in:       os.Stdin,
+		out:      os.Stdout,
+	}
+	cmd.flags.StringVar(&cmd.configFile, "config", configFile, "Config file.")
+	cmd.flags.BoolVar(&cmd.json, "json", false, "Print the output as json.")
+	return cmd
+}
+
+// platformFlags adds the flags selecting the process and credential used
+// for requests to the platform.
+func (cmd *command) platformFlags() *command {
+	cmd.flags.StringVar(&cmd.configId, "config-id", "", "Process id on the platform.")
+	cmd.flags.StringVar(&cmd.authName, "auth-name", "", "Credential name, defaults to the authName of the process with the config id.")
+	return cmd
+}
+
+// parse parses args, flags may also follow the positional arguments. It
+// returns the positional arguments and fails if there aren't exactly n.
+func (cmd *command) parse(args []string, usage string, n int) ([]string, error) {
+	return cmd.parseRange(args, usage, n, n)
+}
+
+// parseRange is like parse, but allows between minArgs and maxArgs positional arguments.
+func (cmd *command) parseRange(args []string, usage string, minArgs, maxArgs int) ([]string, error) {
+	var positional []string
+	for {
+		if err := cmd.flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = cmd.flags.Args()
+		if len(args) == 0 {
+			break
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+	if len(positional) < minArgs || len(positional) > maxArgs {
+		return nil, fmt.Errorf("usage: edi-connector %s %s", cmd.flags.Name(), usage)
+	}
+	return positional, nil
+}
+
+// client returns a platform client configured like the connector with the
+// config file. Without an auth name the auth name of the process with the
+// config id is used.
+func (cmd *command) client() (*platform.Client, error) {
+	cfg, _, err := config.ReadConfigFromFile(cmd.configFile)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.authName == "" && cmd.configId != "" {
+		for _, pc := range slices.Concat(cfg.Outbounds, cfg.Inbounds) {
+			if pc.Id == cmd.configId {
+				cmd.authName = pc.AuthName
+				break
+			}
+		}
+	}
+	credManager, err := credentials.NewFromConfig(cfg.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credentials: %w", err)
+	}
+	return cmd.newClient(cfg, credManager)
+}
+
+// newClient returns a platform client configured like the connector with cfg
+// using the credentials of credManager.
+func (cmd *command) newClient(cfg config.Config, credManager credentials.CredManager) (*platform.Client, error) {
+	level := slog.LevelWarn
+	if cmd.logLevel != "" {
+		var err error
+		level, err = log.ParseLevel(cmd.logLevel)
+		if err != nil {
+			return nil, err
+		}
+	}
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: level}))
+
+	client, err := platform.NewClient(logger, cfg.Url, cfg.CAFile, credManager, cfg.Proxy)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create platform client: %w", err)
+	}
+	return client, nil
+}
+
+// requireConfigId fails if no config id was given.
+func (cmd *command) requireConfigId() error {
+	if cmd.configId == "" {
+		return fmt.Errorf("%s: --config-id is required", cmd.flags.Name())
+	}
+	return nil
+}
+
+// print writes v as json with --json, otherwise text writes it in a human
+// readable form to the output of cmd.
+func (cmd *command) print(v any, text func(w io.Writer)) error {
+	if cmd.json {
+		encoder := json.NewEncoder(cmd.out)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	}
+	w := tabwriter.NewWriter(cmd.out, 0, 4, 2, ' ', 0)
+	text(w)
+	return w.Flush()
+}
+
+// platformCommand runs the subcommand args against the platform of the config file.
+func platformCommand(configFile, logLevel string, args []string) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	name := args[0]
+	if name != "send" {
+		if len(args) < 2 {
+			return fmt.Errorf("usage: edi-connector %s <command>", name)
+		}
+		name, args = name+" "+args[1], args[1:]
+	}
+	cmd := newCommand(name, configFile, logLevel).platformFlags()
+	args = args[1:]
+	switch name {
+	case "transmissions list":
+		return listTransmissions(ctx, cmd, args)
+	case "transmissions download":
+		return downloadTransmission(ctx, cmd, args)
+	case "transmissions confirm":
+		return confirmTransmission(ctx, cmd, args)
+	case "send":
+		return send(ctx, cmd, args)
+	case "attachments upload":
+		return uploadAttachment(ctx, cmd, args)
+	case "attachments list":
+		return listAttachments(ctx, cmd, args)
+	default:
+		return fmt.Errorf("unknown command: %s", name)
+	}
+}
+
+// listTransmissions lists the transmissions waiting for download.
+func listTransmissions(ctx context.Context, cmd *command, args []string) error {
+	if _, err := cmd.parse(args, "--config-id X [--auth-name Y]", 0); err != nil {
+		return err
+	}
+	if err := cmd.requireConfigId(); err != nil {
+		return err
+	}
+	client, err := cmd.client()
+	if err != nil {
+		return err
+	}
+	transmissions, err := client.ListTransmissions(ctx, cmd.configId, cmd.authName)
+	if err != nil {
+		return err
+	}
+	if transmissions == nil {
+		transmissions = []platform.Transmission{}
+	}
+	return cmd.print(transmissions, func(w io.Writer) {
+		fmt.Fprintln(w, "ID\tTEST\tMESSAGES\tURL")
+		for _, t := range transmissions {
+			fmt.Fprintf(w, "%s\t%t\t%s\t%s\n", t.Id, t.Test, strings.Join(t.MessageIds, ","), t.Url)
+		}
+	})
+}
+
+// downloadTransmission writes the content of a transmission waiting for
+// download to stdout or the output file. The transmission isn't confirmed.
+func downloadTransmission(ctx context.Context, cmd *command, args []string) error {
+	output := cmd.flags.String("output", "", "File the transmission is written to, defaults to stdout.")
+	positional, err := cmd.parse(args, "--config-id X [--auth-name Y] [--output file] <id>", 1)
+	if err != nil {
+		return err
+	}
+	if err := cmd.requireConfigId(); err != nil {
+		return err
+	}
+	if cmd.json && *output == "" {
+		return fmt.Errorf("%s: --json requires --output", cmd.flags.Name())
+	}
+	client, err := cmd.client()
+	if err != nil {
+		return err
+	}
+	transmissions, err := client.ListTransmissions(ctx, cmd.configId, cmd.authName)
+	if err != nil {
+		return err
+	}
+	id := positional[0]
+	index := -1
+	for i, t := range transmissions {
+		if t.Id == id {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("transmission %s not found within the transmissions of %s", id, cmd.configId)
+	}
+	transmission := transmissions[index]
+
+	if *output == "" {
+		_, err := client.DownloadTransmissionTo(ctx, transmission, cmd.authName, cmd.out)
+		return err
+	}
+	file, err := os.Create(*output)
+	if err != nil {
+		return fmt.Errorf("failed to create output file: %w", err)
+	}
+	size, err := client.DownloadTransmissionTo(ctx, transmission, cmd.authName, file)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(*output)
+		return err
+	}
+	result := struct {
+		Id   string `json:"id"`
+		File string `json:"file"`
+		Size int64  `json:"size"`
+	}{transmission.Id, *output, size}
+	return cmd.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "downloaded transmission %s to %s (%d bytes)\n", result.Id, result.File, result.Size)
+	})
+}
+
+// confirmTransmission confirms a transmission as processed or failed.
+func confirmTransmission(ctx context.Context, cmd *command, args []string) error {
+	failed := cmd.flags.Bool("error", false, "Confirm the transmission as failed.")
+	message := cmd.flags.String("message", "", "Status or error message of the confirmation.")
+	positional, err := cmd.parse(args, "[--auth-name Y] [--error] [--message M] <id>", 1)
+	if err != nil {
+		return err
+	}
+	client, err := cmd.client()
+	if err != nil {
+		return err
+	}
+	id := positional[0]
+	if *failed {
+		err = client.ConfirmTransmissionError(ctx, id, cmd.authName, *message)
+	} else {
+		err = client.ConfirmTransmission(ctx, id, cmd.authName, *message)
+	}
+	if err != nil {
+		return err
+	}
+	result := struct {
+		Id      string `json:"id"`
+		Error   bool   `json:"error"`
+		Message string `json:"message"`
+	}{id, *failed, *message}
+	return cmd.print(result, func(w io.Writer) {
+		if result.Error {
+			fmt.Fprintf(w, "confirmed transmission %s as failed\n", result.Id)
+			return
+		}
+		fmt.Fprintf(w, "confirmed transmission %s\n", result.Id)
+	})
+}
+
+// send uploads a file as transmission of the process with the config id.
+func send(ctx context.Context, cmd *command, args []string) error {
+	positional, err := cmd.parse(args, "--config-id X [--auth-name Y] <file>", 1)
+	if err != nil {
+		return err
+	}
+	if err := cmd.requireConfigId(); err != nil {
+		return err
+	}
+	client, err := cmd.client()
+	if err != nil {
+		return err
+	}
+	path := positional[0]
+	size, err := uploadFile(path, func(content io.Reader, size int64) error {
+		return client.AddTransmissionStream(ctx, cmd.configId, cmd.authName, content, size)
+	})
+	if err != nil {
+		return err
+	}
+	result := struct {
+		File     string `json:"file"`
+		ConfigId string `json:"configId"`
+		Size     int64  `json:"size"`
+	}{path, cmd.configId, size}
+	return cmd.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "sent %s to %s (%d bytes)\n", result.File, result.ConfigId, result.Size)
+	})
+}
+
+// uploadAttachment uploads a file as attachment, messages refer to it by its file name.
+func uploadAttachment(ctx context.Context, cmd *command, args []string) error {
+	positional, err := cmd.parse(args, "[--auth-name Y] <file>", 1)
+	if err != nil {
+		return err
+	}
+	client, err := cmd.client()
+	if err != nil {
+		return err
+	}
+	path := positional[0]
+	size, err := uploadFile(path, func(content io.Reader, size int64) error {
+		return client.AddAttachmentStream(ctx, content, size, filepath.Base(path), cmd.authName)
+	})
+	if err != nil {
+		return err
+	}
+	result := struct {
+		File     string `json:"file"`
+		Filename string `json:"filename"`
+		Size     int64  `json:"size"`
+	}{path, filepath.Base(path), size}
+	return cmd.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "uploaded attachment %s (%d bytes)\n", result.Filename, result.Size)
+	})
+}
+
+// listAttachments lists the attachments of a message.
+func listAttachments(ctx context.Context, cmd *command, args []string) error {
+	positional, err := cmd.parse(args, "[--auth-name Y] <messageId>", 1)
+	if err != nil {
+		return err
+	}
+	client, err := cmd.client()
+	if err != nil {
+		return err
+	}
+	attachments, err := client.ListMessageAttachments(ctx, positional[0], cmd.authName)
+	if err != nil {
+		return err
+	}
+	return cmd.print(attachments, func(w io.Writer) {
+		fmt.Fprintln(w, "ITEM\tURL")
+		for _, a := range attachments {
+			fmt.Fprintf(w, "%s\t%s\n", a.ItemId, a.Url)
+		}
+	})
+}
+
+// uploadFile passes the content and size of the file at path to upload and
+// returns the size.
+func uploadFile(path string, upload func(io.Reader, int64) error) (int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file: %w", err)
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat file: %w", err)
+	}
+	return info.Size(), upload(file, info.Size())
+}
+
+// run runs the connector, with --once every transport is run a single time
+// and a summary of the transfers is printed.
+func run(configFile, logLevel string, dryRun bool, args []string) error {
+	cmd := newCommand("run", configFile, logLevel)
+	once := cmd.flags.Bool("once", false, "Run every transport once and exit.")
+	configIds := cmd.flags.String("config-id", "", "Comma separated process ids to run with --once, defaults to all processes.")
+	cmd.flags.BoolVar(&dryRun, "dry-run", dryRun, "Log the transfers instead of executing them.")
+	if _, err := cmd.parse(args, "[--dry-run] [--once [--config-id X,Y] [--json]]", 0); err != nil {
+		return err
+	}
+	if !*once {
+		return execute(cmd.configFile, cmd.logLevel, dryRun)
+	}
+
+	cfg, configFile, err := config.ReadConfigFromFile(cmd.configFile)
+	if err != nil {
+		return err
+	}
+	if cmd.logLevel != "" {
+		cfg.Log.Level = cmd.logLevel
+	}
+	cfg.DryRun = dryRun
+	logger, err := log.NewFromConfig(cfg.Log)
+	if err != nil {
+		return err
+	}
+	logger.Info("client", "version", version.Version)
+	logger.Info("Loaded config from", "path", configFile)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cl, err := connector.New(logger, cfg)
+	if err != nil {
+		return fmt.Errorf("failed to create edi-connector: %w", err)
+	}
+	var ids []string
+	if *configIds != "" {
+		ids = strings.Split(*configIds, ",")
+	}
+	summaries, runErr := cl.RunOnce(ctx, ids...)
+	if summaries == nil {
+		summaries = []connector.RunSummary{}
+	}
+	err = cmd.print(summaries, func(w io.Writer) {
+		fmt.Fprintln(w, "KIND\tCONFIG ID\tUPLOADED\tDOWNLOADED\tFAILED\tDURATION\tERROR")
+		for _, s := range summaries {
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n", s.Kind, s.ConfigId, s.Uploaded, s.Downloaded, s.Failed, s.Duration, s.Error)
+		}
+	})
+	if runErr != nil {
+		return runErr
+	}
+	return err
+}
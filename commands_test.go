@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"testing"
+)
+
+// testPlatform serves the transmissions t1 and t2 of the process in, the
+// content of t2 doesn't match its hash.
+type testPlatform struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	usernames []string
+	sent      []byte
+}
+
+func startPlatform(t *testing.T) *testPlatform {
+	t.Helper()
+	p := &testPlatform{}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /v2/transmissions", func(w http.ResponseWriter, r *http.Request) {
+		p.record(r)
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"transmissions": []map[string]any{
+				{"id": "t1", "url": p.URL + "/download/t1"},
+				{"id": "t2", "url": p.URL + "/download/t2", "hash": map[string]string{"method": "SHA-256", "sum": "0000"}},
+			},
+		})
+	})
+	mux.HandleFunc("GET /download/{id}", func(w http.ResponseWriter, r *http.Request) {
+		p.record(r)
+		w.Write([]byte("content of " + r.PathValue("id")))
+	})
+	mux.HandleFunc("POST /v2/transmissions", func(w http.ResponseWriter, r *http.Request) {
+		p.record(r)
+		data, _ := io.ReadAll(r.Body)
+		p.mu.Lock()
+		p.sent = data
+		p.mu.Unlock()
+	})
+	p.Server = httptest.NewServer(mux)
+	t.Cleanup(p.Close)
+	return p
+}
+
+func (p *testPlatform) record(r *http.Request) {
+	username, _, _ := r.BasicAuth()
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.usernames = append(p.usernames, username)
+}
+
+// writePlatformConfig writes a config using the platform at url with the
+// inbound process in using the credential special.
+func writePlatformConfig(t *testing.T, url string) string {
+	t.Helper()
+	t.Setenv("EDI_CONNECTOR", "user:password")
+	t.Setenv("EDI_CONNECTOR_SPECIAL", "special:secret")
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+url: ` + url + `
+credentials:
+  type: ENV
+inbounds:
+- id: in
+  authName: special
+  type: FILE
+  settings:
+    path: ` + t.TempDir() + `
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return configFile
+}
+
+func TestParseFlagsAfterPositionals(t *testing.T) {
+	cmd := newCommand("transmissions download", "", "").platformFlags()
+	output := cmd.flags.String("output", "", "")
+	positional, err := cmd.parse([]string{"t1", "--config-id", "in", "--output", "out.edi", "--json"}, "<id>", 1)
+	if err != nil {
+		t.Fatalf("Failed to parse arguments: %v", err)
+	}
+	if !slices.Equal(positional, []string{"t1"}) {
+		t.Errorf("Expected positional arguments [t1], got: %v", positional)
+	}
+	if cmd.configId != "in" || *output != "out.edi" || !cmd.json {
+		t.Errorf("Expected flags after the positional argument to be parsed, got config id %q, output %q, json %t", cmd.configId, *output, cmd.json)
+	}
+
+	for _, args := range [][]string{{}, {"t1", "t2"}} {
+		cmd := newCommand("transmissions download", "", "").platformFlags()
+		if _, err := cmd.parse(args, "<id>", 1); err == nil {
+			t.Errorf("Expected error parsing %v", args)
+		}
+	}
+}
+
+func TestListTransmissionsCommand(t *testing.T) {
+	platform := startPlatform(t)
+	configFile := writePlatformConfig(t, platform.URL)
+
+	var out bytes.Buffer
+	cmd := newCommand("transmissions list", configFile, "").platformFlags()
+	cmd.out = &out
+	if err := listTransmissions(t.Context(), cmd, []string{"--config-id", "in", "--json"}); err != nil {
+		t.Fatalf("Failed to list transmissions: %v", err)
+	}
+
+	var transmissions []struct {
+		Id  string `json:"id"`
+		Url string `json:"url"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &transmissions); err != nil {
+		t.Fatalf("Expected json array, got %q: %v", out.String(), err)
+	}
+	if len(transmissions) != 2 || transmissions[0].Id != "t1" || transmissions[1].Id != "t2" {
+		t.Errorf("Expected transmissions t1 and t2, got: %+v", transmissions)
+	}
+	// the auth name is resolved from the process with the config id
+	if !slices.Equal(platform.usernames, []string{"special"}) {
+		t.Errorf("Expected request with the credential of the process, got usernames: %v", platform.usernames)
+	}
+}
+
+func TestDownloadTransmissionCommand(t *testing.T) {
+	platform := startPlatform(t)
+	configFile := writePlatformConfig(t, platform.URL)
+	dir := t.TempDir()
+
+	var out bytes.Buffer
+	output := filepath.Join(dir, "t1.edi")
+	cmd := newCommand("transmissions download", configFile, "").platformFlags()
+	cmd.out = &out
+	if err := downloadTransmission(t.Context(), cmd, []string{"t1", "--config-id", "in", "--output", output, "--json"}); err != nil {
+		t.Fatalf("Failed to download transmission: %v", err)
+	}
+	data, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatalf("Failed to read output file: %v", err)
+	}
+	if string(data) != "content of t1" {
+		t.Errorf("Expected content of t1 within the output file, got: %q", data)
+	}
+	var result map[string]any
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("Expected json object, got %q: %v", out.String(), err)
+	}
+	expected := map[string]any{"id": "t1", "file": output, "size": float64(len(data))}
+	if len(result) != len(expected) || result["id"] != expected["id"] || result["file"] != expected["file"] || result["size"] != expected["size"] {
+		t.Errorf("Expected result %v, got: %v", expected, result)
+	}
+
+	output = filepath.Join(dir, "t2.edi")
+	cmd = newCommand("transmissions download", configFile, "").platformFlags()
+	cmd.out = io.Discard
+	if err := downloadTransmission(t.Context(), cmd, []string{"--config-id", "in", "--output", output, "t2"}); err == nil {
+		t.Fatal("Expected error downloading transmission with hash mismatch")
+	}
+	if _, err := os.Stat(output); !os.IsNotExist(err) {
+		t.Errorf("Expected output file of failed download to be removed, got: %v", err)
+	}
+}
+
+func TestSendCommand(t *testing.T) {
+	platform := startPlatform(t)
+	configFile := writePlatformConfig(t, platform.URL)
+	file := filepath.Join(t.TempDir(), "message.edi")
+	if err := os.WriteFile(file, []byte("message"), 0644); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd := newCommand("send", configFile, "").platformFlags()
+	cmd.out = &out
+	if err := send(t.Context(), cmd, []string{file, "--config-id", "in", "--json"}); err != nil {
+		t.Fatalf("Failed to send file: %v", err)
+	}
+	if string(platform.sent) != "message" {
+		t.Errorf("Expected platform to receive the message, got: %q", platform.sent)
+	}
+	var result struct {
+		File     string `json:"file"`
+		ConfigId string `json:"configId"`
+		Size     int64  `json:"size"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("Expected json object, got %q: %v", out.String(), err)
+	}
+	if result.File != file || result.ConfigId != "in" || result.Size != int64(len("message")) {
+		t.Errorf("Expected result of the sent file, got: %+v", result)
+	}
+}
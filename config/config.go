@@ -17,6 +17,52 @@ type ProcessConfig struct {
 	Type     string         `json:"type" yaml:"type"`
 	AuthName string         `json:"authName" yaml:"authName"`
 	Settings map[string]any `json:"settings" yaml:"settings"`
+	// RunWaitTime overrides the global runWaitTime for this process.
+	RunWaitTime string `json:"runWaitTime" yaml:"runWaitTime"`
+	// Concurrency is the number of transfers of this process running at the same time, defaults to 1.
+	Concurrency int            `json:"concurrency" yaml:"concurrency"`
+	Schedule    ScheduleConfig `json:"schedule" yaml:"schedule"`
+}
+
+// ScheduleConfig restricts when a process is run.
+type ScheduleConfig struct {
+	// Cron runs the process on a cron expression instead of the runWaitTime interval.
+	Cron string `json:"cron" yaml:"cron"`
+	// TimeZone the cron expression and blackouts are evaluated in, defaults to the local time zone.
+	TimeZone  string           `json:"timeZone" yaml:"timeZone"`
+	Blackouts []BlackoutConfig `json:"blackouts" yaml:"blackouts"`
+}
+
+// BlackoutConfig is a recurring window the process isn't run in, e.g.
+// days ["SUN"] from "02:00" to "04:00". A window with from after to ends on the next day.
+type BlackoutConfig struct {
+	Days []string `json:"days" yaml:"days"`
+	From string   `json:"from" yaml:"from"`
+	To   string   `json:"to" yaml:"to"`
+}
+
+// TracingConfig configures the export of traces.
+type TracingConfig struct {
+	// Endpoint is the url of an OTLP/HTTP collector, e.g. "http://localhost:4318".
+	// Tracing is disabled without an endpoint.
+	Endpoint string `json:"endpoint" yaml:"endpoint"`
+	// SampleRatio is the fraction of traces exported, defaults to all traces.
+	SampleRatio *float64 `json:"sampleRatio" yaml:"sampleRatio"`
+}
+
+// CredentialsConfig selects the backend storing the credentials of the auth names.
+type CredentialsConfig struct {
+	// Type is ENV for environment variables or FILE for an encrypted file.
+	// Defaults to the windows credential manager on windows and ENV otherwise.
+	Type string `json:"type" yaml:"type"`
+	// File holds the credentials of type FILE, it is created on the first stored credential.
+	File string `json:"file" yaml:"file"`
+	// KeyFile contains the base64 encoded 256 bit key of the credential file,
+	// e.g. created with "openssl rand -base64 32".
+	KeyFile string `json:"keyFile" yaml:"keyFile"`
+	// PassphraseFile contains a passphrase the key of the credential file is
+	// derived from, alternatively to KeyFile.
+	PassphraseFile string `json:"passphraseFile" yaml:"passphraseFile"`
 }
 
 type LogOptions struct {
@@ -34,6 +80,31 @@ type Config struct {
 	Log          LogOptions      `json:"log" yaml:"log"`
 	Url          string          `json:"url" yaml:"url"`
 	CAFile       string          `json:"caFile" yaml:"caFile"`
+	// DataDir holds the state of the connector, defaults to the folder "data" next to the config file.
+	DataDir string `json:"dataDir" yaml:"dataDir"`
+	// MaxDeliveryAttempts is the number of failed local deliveries after which
+	// an inbound transmission is confirmed as failed.
+	MaxDeliveryAttempts int `json:"maxDeliveryAttempts" yaml:"maxDeliveryAttempts"`
+	// DuplicateWindow is the time the content of an uploaded message is
+	// remembered to skip uploads of identical messages, e.g. "24h". Skipped
+	// messages are moved to the duplicate folder of the transport. The check
+	// is disabled if empty or zero, the default.
+	DuplicateWindow string `json:"duplicateWindow" yaml:"duplicateWindow"`
+	// MaxConcurrency is the number of processes running at the same time.
+	MaxConcurrency int `json:"maxConcurrency" yaml:"maxConcurrency"`
+	// TransferTimeout limits a single request or delivery of a transfer.
+	TransferTimeout string `json:"transferTimeout" yaml:"transferTimeout"`
+	// AdminToken authorizes requests to the admin api on the instance port.
+	// If empty a token is generated into the file admin.token within the data dir.
+	AdminToken string `json:"adminToken" yaml:"adminToken"`
+	// MetricsAddress additionally serves the metrics and health endpoints on
+	// this address, e.g. ":9644". They are always served on the instance port.
+	MetricsAddress string            `json:"metricsAddress" yaml:"metricsAddress"`
+	Tracing        TracingConfig     `json:"tracing" yaml:"tracing"`
+	Credentials    CredentialsConfig `json:"credentials" yaml:"credentials"`
+	// DryRun only logs the transfers instead of executing them, it is set
+	// with the --dry-run flag.
+	DryRun bool `json:"-" yaml:"-"`
 }
 
 type Format int
@@ -49,50 +120,72 @@ const (
 )
 
 func ReadConfigFromFile(configFile string) (Config, string, error) {
+	configFile, format, err := findConfigFile(configFile)
+	if err != nil {
+		return Config{}, "", err
+	}
+	file, err := os.Open(configFile)
+	if err != nil {
+		return Config{}, "", fmt.Errorf("failed to read config file: %w", err)
+	}
+	defer file.Close()
+	config, err := ReadConfig(file, format)
+	if err != nil {
+		return Config{}, "", err
+	}
+	applyFileDefaults(&config, configFile)
+	return config, configFile, nil
+}
+
+// findConfigFile returns configFile and its format. Without a json or yaml
+// configFile the working directory and the system config folder are searched.
+func findConfigFile(configFile string) (string, Format, error) {
 	format := formatFromFileName(configFile)
-	if format == Error {
-		workdir, err := os.Getwd()
+	if format != Error {
+		return configFile, format, nil
+	}
+	workdir, err := os.Getwd()
+	if err != nil {
+		return "", Error, fmt.Errorf("failed to get working directory: %w", err)
+	}
+	searchLocations := []string{workdir}
+	switch {
+	case runtime.GOOS == "windows":
+		searchLocations = append(searchLocations, filepath.Join(os.Getenv("ProgramData"), "myOpenFactory Software GmbH", "EDI-Connector"))
+	case runtime.GOOS == "linux":
+		searchLocations = append(searchLocations, filepath.Join("etc", "myopenfactory", "edi-connector"))
+	}
+
+	for _, searchLocation := range searchLocations {
+		entires, err := os.ReadDir(searchLocation)
 		if err != nil {
-			return Config{}, "", fmt.Errorf("failed to get working directory: %w", err)
-		}
-		searchLocations := []string{workdir}
-		switch {
-		case runtime.GOOS == "windows":
-			searchLocations = append(searchLocations, filepath.Join(os.Getenv("ProgramData"), "myOpenFactory Software GmbH", "EDI-Connector"))
-		case runtime.GOOS == "linux":
-			searchLocations = append(searchLocations, filepath.Join("etc", "myopenfactory", "edi-connector"))
+			if os.IsNotExist(err) {
+				continue
+			}
+			return "", Error, fmt.Errorf("failed to list config directory: %w", err)
 		}
-
-		for _, searchLocation := range searchLocations {
-			entires, err := os.ReadDir(searchLocation)
-			if err != nil {
-				if os.IsNotExist(err) {
-					continue
-				}
-				return Config{}, "", fmt.Errorf("failed to list config directory: %w", err)
+		for _, entry := range entires {
+			if entry.IsDir() {
+				continue
 			}
-			for _, entry := range entires {
-				if entry.IsDir() {
-					continue
-				}
-				format = formatFromFileName(entry.Name())
-				if format != Error {
-					configFile = filepath.Join(searchLocation, entry.Name())
-					break
-				}
+			format = formatFromFileName(entry.Name())
+			if format != Error {
+				configFile = filepath.Join(searchLocation, entry.Name())
+				break
 			}
 		}
-		if format == Error {
-			return Config{}, "", fmt.Errorf("no config file found")
-		}
 	}
-	file, err := os.Open(configFile)
-	if err != nil {
-		return Config{}, "", fmt.Errorf("failed to read config file: %w", err)
+	if format == Error {
+		return "", Error, fmt.Errorf("no config file found")
+	}
+	return configFile, format, nil
+}
+
+// applyFileDefaults sets the defaults of config depending on the location of configFile.
+func applyFileDefaults(config *Config, configFile string) {
+	if config.DataDir == "" {
+		config.DataDir = filepath.Join(filepath.Dir(configFile), "data")
 	}
-	defer file.Close()
-	config, err := ReadConfig(file, format)
-	return config, configFile, err
 }
 
 func formatFromFileName(fileName string) Format {
@@ -109,6 +202,9 @@ func ReadConfig(configReader io.Reader, format Format) (Config, error) {
 	var cfg Config
 	cfg.RunWaitTime = "1m"
 	cfg.Url = "https://rest.ediplatform.services"
+	cfg.MaxDeliveryAttempts = 5
+	cfg.MaxConcurrency = 4
+	cfg.TransferTimeout = "15s"
 	if proxy := os.Getenv("HTTP_PROXY"); proxy != "" {
 		cfg.Proxy = proxy
 	}
@@ -118,6 +118,18 @@ func TestNewConfigFromEmpty(t *testing.T) {
 	if cfg.Url != "https://rest.ediplatform.services" {
 		t.Errorf("wrong url wanted 'https://rest.ediplatform.services' got: %v", cfg.Url)
 	}
+	if cfg.MaxDeliveryAttempts != 5 {
+		t.Errorf("wrong maxDeliveryAttempts wanted 5 got: %v", cfg.MaxDeliveryAttempts)
+	}
+	if cfg.DuplicateWindow != "" {
+		t.Errorf("wrong duplicateWindow wanted '' got: %v", cfg.DuplicateWindow)
+	}
+	if cfg.MaxConcurrency != 4 {
+		t.Errorf("wrong maxConcurrency wanted 4 got: %v", cfg.MaxConcurrency)
+	}
+	if cfg.TransferTimeout != "15s" {
+		t.Errorf("wrong transferTimeout wanted '15s' got: %v", cfg.TransferTimeout)
+	}
 	if runtime.GOOS == "windows" {
 		if cfg.Log.Type != "EVENT" {
 			t.Errorf("wrong log type wanted 'EVENT' got: %v", cfg.Log.Type)
@@ -0,0 +1,302 @@
+package config
+
+import (
+	"bytes"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"net/url"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Problem is an invalid setting found while validating a config.
+type Problem struct {
+	// Path is the JSON path of the setting, e.g. "$.outbounds[0].runWaitTime".
+	Path    string
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Path + ": " + p.Message
+}
+
+// Problemf returns a problem of the setting at path.
+func Problemf(path string, format string, args ...any) Problem {
+	return Problem{Path: path, Message: fmt.Sprintf(format, args...)}
+}
+
+// ValidateFile reads the config like ReadConfigFromFile and returns all
+// problems found in it: unknown fields, values of the wrong type and the
+// problems reported by Validate. An error is only returned if the file
+// can't be read.
+func ValidateFile(configFile string) (Config, string, []Problem, error) {
+	configFile, format, err := findConfigFile(configFile)
+	if err != nil {
+		return Config{}, "", nil, err
+	}
+	data, err := os.ReadFile(configFile)
+	if err != nil {
+		return Config{}, "", nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var raw any
+	switch format {
+	case Json:
+		err = json.Unmarshal(data, &raw)
+	case Yaml:
+		err = yaml.Unmarshal(data, &raw)
+	}
+	if err != nil {
+		return Config{}, configFile, []Problem{Problemf("$", "failed to parse config file: %v", err)}, nil
+	}
+	problems := UnknownFields("$", raw, Config{})
+
+	cfg, err := ReadConfig(bytes.NewReader(data), format)
+	if err != nil {
+		return Config{}, configFile, append(problems, decodeProblem(err)), nil
+	}
+	applyFileDefaults(&cfg, configFile)
+	return cfg, configFile, append(problems, Validate(cfg)...), nil
+}
+
+// decodeProblem returns the problem of a failed decoding of the config file.
+func decodeProblem(err error) Problem {
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return Problemf("$."+typeErr.Field, "expected %s, got %s", typeErr.Type, typeErr.Value)
+	}
+	return Problemf("$", "%v", err)
+}
+
+// UnknownFields returns a problem for each field of source, decoded from
+// json or yaml at path, which doesn't exist in target. Fields of maps with
+// arbitrary values like the process settings aren't checked.
+func UnknownFields(path string, source any, target any) []Problem {
+	return unknownFields(path, source, reflect.TypeOf(target))
+}
+
+func unknownFields(path string, source any, t reflect.Type) []Problem {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var problems []Problem
+	switch t.Kind() {
+	case reflect.Struct:
+		values, ok := source.(map[string]any)
+		if !ok {
+			return nil
+		}
+		fields := jsonFields(t)
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			field, ok := fields[name]
+			if !ok {
+				problems = append(problems, Problemf(path+"."+name, "unknown field"))
+				continue
+			}
+			problems = append(problems, unknownFields(path+"."+name, values[name], field.Type)...)
+		}
+	case reflect.Slice:
+		values, ok := source.([]any)
+		if !ok {
+			return nil
+		}
+		for i, value := range values {
+			problems = append(problems, unknownFields(fmt.Sprintf("%s[%d]", path, i), value, t.Elem())...)
+		}
+	case reflect.Map:
+		values, ok := source.(map[string]any)
+		if !ok || t.Elem().Kind() == reflect.Interface {
+			return nil
+		}
+		for _, name := range slices.Sorted(maps.Keys(values)) {
+			problems = append(problems, unknownFields(path+"."+name, values[name], t.Elem())...)
+		}
+	}
+	return problems
+}
+
+// jsonFields returns the fields of the struct type t by their json name,
+// including the fields of embedded structs.
+func jsonFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" && field.Type.Kind() == reflect.Struct {
+			maps.Copy(fields, jsonFields(field.Type))
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field
+	}
+	return fields
+}
+
+// Validate returns the problems of the settings of cfg, which can be checked
+// without knowing the transports.
+func Validate(cfg Config) []Problem {
+	var problems []Problem
+	add := func(path string, format string, args ...any) {
+		problems = append(problems, Problemf(path, format, args...))
+	}
+
+	if cfg.InstancePort < 0 || cfg.InstancePort > 65535 {
+		add("$.instancePort", "port %d out of range", cfg.InstancePort)
+	}
+	if d, err := time.ParseDuration(cfg.RunWaitTime); err != nil {
+		add("$.runWaitTime", "invalid duration %q", cfg.RunWaitTime)
+	} else if d <= 0 {
+		add("$.runWaitTime", "duration must be positive")
+	}
+	if err := checkDuration(cfg.DuplicateWindow); err != nil {
+		add("$.duplicateWindow", "%v", err)
+	}
+	if err := checkDuration(cfg.TransferTimeout); err != nil {
+		add("$.transferTimeout", "%v", err)
+	}
+	if cfg.MaxDeliveryAttempts < 0 {
+		add("$.maxDeliveryAttempts", "must not be negative")
+	}
+	if cfg.MaxConcurrency < 0 {
+		add("$.maxConcurrency", "must not be negative")
+	}
+	if err := checkUrl(cfg.Url); err != nil {
+		add("$.url", "%v", err)
+	}
+	if cfg.Proxy != "" {
+		if err := checkUrl(cfg.Proxy); err != nil {
+			add("$.proxy", "%v", err)
+		}
+	}
+	if cfg.CAFile != "" {
+		if pem, err := os.ReadFile(cfg.CAFile); err != nil {
+			add("$.caFile", "failed to read ca file: %v", err)
+		} else if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			add("$.caFile", "no PEM encoded certificates found in %s", cfg.CAFile)
+		}
+	}
+
+	if cfg.Log.Level != "" {
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(cfg.Log.Level)); err != nil {
+			add("$.log.level", "invalid log level %q", cfg.Log.Level)
+		}
+	}
+	switch cfg.Log.Type {
+	case "FILE":
+		if cfg.Log.Folder == "" {
+			add("$.log.folder", "log folder required for log type FILE")
+		}
+	case "EVENT", "STDOUT_TEXT", "STDOUT_JSON":
+	default:
+		add("$.log.type", "unknown log type %q, expected FILE, EVENT, STDOUT_TEXT or STDOUT_JSON", cfg.Log.Type)
+	}
+
+	if cfg.Tracing.Endpoint != "" {
+		if err := checkUrl(cfg.Tracing.Endpoint); err != nil {
+			add("$.tracing.endpoint", "%v", err)
+		}
+	}
+	if ratio := cfg.Tracing.SampleRatio; ratio != nil && (*ratio < 0 || *ratio > 1) {
+		add("$.tracing.sampleRatio", "must be between 0 and 1")
+	}
+
+	switch cfg.Credentials.Type {
+	case "", "ENV":
+	case "FILE":
+		if cfg.Credentials.File == "" {
+			add("$.credentials.file", "credential file required for credentials type FILE")
+		}
+		if (cfg.Credentials.KeyFile == "") == (cfg.Credentials.PassphraseFile == "") {
+			add("$.credentials", "either keyFile or passphraseFile required for credentials type FILE")
+		}
+	default:
+		add("$.credentials.type", "unknown credentials type %q, expected ENV or FILE", cfg.Credentials.Type)
+	}
+
+	problems = append(problems, validateProcesses("$.outbounds", cfg.Outbounds)...)
+	problems = append(problems, validateProcesses("$.inbounds", cfg.Inbounds)...)
+	return problems
+}
+
+// validateProcesses returns the problems of the processes listed at path.
+func validateProcesses(path string, processes []ProcessConfig) []Problem {
+	var problems []Problem
+	ids := make(map[string]int)
+	for i, pc := range processes {
+		path := fmt.Sprintf("%s[%d]", path, i)
+		if pc.Id == "" {
+			problems = append(problems, Problemf(path+".id", "process id required"))
+		} else if first, ok := ids[pc.Id]; ok {
+			problems = append(problems, Problemf(path+".id", "duplicate process id %q, already used by [%d]", pc.Id, first))
+		} else {
+			ids[pc.Id] = i
+		}
+		if pc.Type == "" {
+			problems = append(problems, Problemf(path+".type", "transport type required"))
+		}
+		if pc.RunWaitTime != "" {
+			if d, err := time.ParseDuration(pc.RunWaitTime); err != nil {
+				problems = append(problems, Problemf(path+".runWaitTime", "invalid duration %q", pc.RunWaitTime))
+			} else if d <= 0 {
+				problems = append(problems, Problemf(path+".runWaitTime", "duration must be positive"))
+			}
+		}
+		if pc.Concurrency < 0 {
+			problems = append(problems, Problemf(path+".concurrency", "must not be negative"))
+		}
+	}
+	return problems
+}
+
+// checkDuration verifies the optional duration value isn't invalid or negative.
+func checkDuration(value string) error {
+	if value == "" {
+		return nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", value)
+	}
+	if d < 0 {
+		return fmt.Errorf("duration must not be negative")
+	}
+	return nil
+}
+
+// CheckDuration returns a problem at path if the optional duration value is invalid.
+func CheckDuration(path string, value string) []Problem {
+	if err := checkDuration(value); err != nil {
+		return []Problem{Problemf(path, "%v", err)}
+	}
+	return nil
+}
+
+// checkUrl verifies value is an absolute http or https url.
+func checkUrl(value string) error {
+	u, err := url.Parse(value)
+	if err != nil {
+		return fmt.Errorf("invalid url %q: %w", value, err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("invalid url %q, expected an url like https://host:port", value)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestValidateFile(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+runWaitTime: 5x
+proxy: localhost
+log:
+  level: LOUD
+  colour: true
+outbounds:
+- id: out
+  type: FILE
+  schedules: {}
+- id: out
+  type: FILE
+  runWaitTime: -1m
+  settings:
+    anything: goes
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	cfg, _, problems, err := ValidateFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to validate config file: %v", err)
+	}
+	if len(cfg.Outbounds) != 2 {
+		t.Errorf("Expected 2 outbounds, got: %d", len(cfg.Outbounds))
+	}
+	var paths []string
+	for _, problem := range problems {
+		paths = append(paths, problem.Path)
+	}
+	expected := []string{
+		"$.log.colour",
+		"$.outbounds[0].schedules",
+		"$.runWaitTime",
+		"$.proxy",
+		"$.log.level",
+		"$.outbounds[1].id",
+		"$.outbounds[1].runWaitTime",
+	}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("Expected problems at %v, got: %v", expected, problems)
+	}
+}
+
+func TestValidateFileTypeMismatch(t *testing.T) {
+	configFile := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(configFile, []byte(`{"maxConcurrency": "four"}`), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+
+	_, _, problems, err := ValidateFile(configFile)
+	if err != nil {
+		t.Fatalf("Failed to validate config file: %v", err)
+	}
+	if len(problems) != 1 || problems[0].Path != "$.maxConcurrency" {
+		t.Errorf("Expected problem at $.maxConcurrency, got: %v", problems)
+	}
+}
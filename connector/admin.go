@@ -0,0 +1,271 @@
+package connector
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/log"
+	"github.com/myopenfactory/edi-connector/v2/metrics"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+)
+
+// adminTokenFile holds the generated admin token within the data dir.
+const adminTokenFile = "admin.token"
+
+// adminToken returns the token of the admin api configured in cfg or stored
+// within the data dir. A missing token is generated, without a data dir to
+// store it the admin api rejects all requests.
+func adminToken(cfg config.Config) (string, error) {
+	if cfg.AdminToken != "" {
+		return cfg.AdminToken, nil
+	}
+	if cfg.DataDir == "" {
+		return "", nil
+	}
+	path := filepath.Join(cfg.DataDir, adminTokenFile)
+	data, err := os.ReadFile(path)
+	if err == nil && strings.TrimSpace(string(data)) != "" {
+		return strings.TrimSpace(string(data)), nil
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return "", fmt.Errorf("failed to read admin token: %w", err)
+	}
+
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate admin token: %w", err)
+	}
+	token := hex.EncodeToString(b)
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", fmt.Errorf("failed to write admin token: %w", err)
+	}
+	return token, nil
+}
+
+// adminHandler serves the admin api, the metrics and the health endpoints.
+// All requests to the api have to carry the admin token as bearer token.
+func (c *Connector) adminHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("/api/", c.authorize(c.apiHandler()))
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /healthz", c.handleHealth)
+	mux.HandleFunc("GET /readyz", c.handleReady)
+	return mux
+}
+
+// metricsHandler serves the metrics and the health endpoints on the dedicated
+// metrics address.
+func (c *Connector) metricsHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /healthz", c.handleHealth)
+	mux.HandleFunc("GET /readyz", c.handleReady)
+	return mux
+}
+
+func (c *Connector) apiHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/transports", c.handleTransports)
+	mux.HandleFunc("POST /api/transports/{kind}/{configId}/pause", c.handlePause(true))
+	mux.HandleFunc("POST /api/transports/{kind}/{configId}/resume", c.handlePause(false))
+	mux.HandleFunc("POST /api/transports/{kind}/{configId}/run", c.handleRun)
+	mux.HandleFunc("GET /api/errors", c.handleErrors)
+	mux.HandleFunc("POST /api/errors/{configId}/{name}/requeue", c.handleRequeue)
+	mux.HandleFunc("GET /api/log/level", c.handleLogLevel)
+	mux.HandleFunc("PUT /api/log/level", c.handleSetLogLevel)
+	return mux
+}
+
+func (c *Connector) authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || c.adminToken == "" || subtle.ConstantTimeCompare([]byte(token), []byte(c.adminToken)) != 1 {
+			writeError(w, http.StatusUnauthorized, errors.New("invalid or missing admin token"))
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// serve serves handler on listener until the returned function is called.
+func (c *Connector) serve(ctx context.Context, name string, listener net.Listener, handler http.Handler) func() {
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			c.logger.Error(name+" stopped", "error", err)
+		}
+	}()
+	c.logger.Info("serving "+name, "address", listener.Addr().String())
+
+	return func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(shutdownCtx); err != nil {
+			c.logger.Error("failed to shutdown "+name, "error", err)
+		}
+		<-done
+	}
+}
+
+func (c *Connector) handleTransports(w http.ResponseWriter, r *http.Request) {
+	statuses := make([]workerStatus, 0)
+	for _, wk := range c.snapshot() {
+		statuses = append(statuses, wk.status())
+	}
+	writeJSON(w, http.StatusOK, statuses)
+}
+
+func (c *Connector) handlePause(paused bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		wk := c.findWorker(r.PathValue("kind"), r.PathValue("configId"))
+		if wk == nil {
+			writeError(w, http.StatusNotFound, errors.New("transport not found"))
+			return
+		}
+		wk.setPaused(paused)
+		c.logger.Info("changed transport state via admin api", "kind", wk.kind, "configId", wk.configId, "paused", paused)
+		writeJSON(w, http.StatusOK, wk.status())
+	}
+}
+
+func (c *Connector) handleRun(w http.ResponseWriter, r *http.Request) {
+	wk := c.findWorker(r.PathValue("kind"), r.PathValue("configId"))
+	if wk == nil {
+		writeError(w, http.StatusNotFound, errors.New("transport not found"))
+		return
+	}
+	if wk.isPaused() {
+		writeError(w, http.StatusConflict, errors.New("transport is paused"))
+		return
+	}
+	wk.wake()
+	c.logger.Info("triggered transport run via admin api", "kind", wk.kind, "configId", wk.configId)
+	writeJSON(w, http.StatusAccepted, wk.status())
+}
+
+// failedObject is a file within the error folder of an outbound transport.
+// An entry holding an error reports an error folder which couldn't be listed.
+type failedObject struct {
+	ConfigId string    `json:"configId"`
+	Name     string    `json:"name,omitempty"`
+	Size     int64     `json:"size,omitempty"`
+	ModTime  time.Time `json:"modTime,omitzero"`
+	Error    string    `json:"error,omitempty"`
+}
+
+func (c *Connector) handleErrors(w http.ResponseWriter, r *http.Request) {
+	failed := make([]failedObject, 0)
+	for _, outbound := range c.outbounds() {
+		requeuer, ok := outbound.(transport.Requeuer)
+		if !ok {
+			continue
+		}
+		objects, err := requeuer.Failed(r.Context())
+		if err != nil {
+			c.logger.Error("failed to list errors via admin api", "configId", outbound.ConfigId(), "error", err)
+			failed = append(failed, failedObject{
+				ConfigId: outbound.ConfigId(),
+				Error:    err.Error(),
+			})
+			continue
+		}
+		for _, obj := range objects {
+			failed = append(failed, failedObject{
+				ConfigId: outbound.ConfigId(),
+				Name:     obj.Name,
+				Size:     obj.Size,
+				ModTime:  obj.ModTime,
+			})
+		}
+	}
+	writeJSON(w, http.StatusOK, failed)
+}
+
+func (c *Connector) handleRequeue(w http.ResponseWriter, r *http.Request) {
+	configId := r.PathValue("configId")
+	for _, outbound := range c.outbounds() {
+		if outbound.ConfigId() != configId {
+			continue
+		}
+		requeuer, ok := outbound.(transport.Requeuer)
+		if !ok {
+			writeError(w, http.StatusBadRequest, errors.New("transport does not support requeuing"))
+			return
+		}
+		name := r.PathValue("name")
+		if err := requeuer.Requeue(r.Context(), name); err != nil {
+			status := http.StatusInternalServerError
+			if errors.Is(err, os.ErrNotExist) {
+				status = http.StatusNotFound
+			}
+			writeError(w, status, err)
+			return
+		}
+		c.logger.Info("requeued failed file via admin api", "configId", configId, "name", name)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	writeError(w, http.StatusNotFound, errors.New("transport not found"))
+}
+
+// logLevel is the body of the log level requests.
+type logLevel struct {
+	Level string `json:"level"`
+}
+
+func (c *Connector) handleLogLevel(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, logLevel{Level: log.Level().String()})
+}
+
+func (c *Connector) handleSetLogLevel(w http.ResponseWriter, r *http.Request) {
+	var body logLevel
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, fmt.Errorf("failed to decode request: %w", err))
+		return
+	}
+	level, err := log.ParseLevel(body.Level)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	log.SetLevel(level)
+	c.logger.Info("changed log level via admin api", "level", level)
+	writeJSON(w, http.StatusOK, logLevel{Level: level.String()})
+}
+
+// findWorker returns the worker of the transport of kind with configId.
+func (c *Connector) findWorker(kind, configId string) *worker {
+	for _, wk := range c.snapshot() {
+		if wk.kind == kind && wk.configId == configId {
+			return wk
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,236 @@
+package connector_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/connector"
+	"github.com/myopenfactory/edi-connector/v2/log"
+)
+
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("Failed to find free port: %v", err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+// testConfig returns the config of a connector with a FILE outbound
+// transport out reading from messageDir and moving failed files to errorDir.
+func testConfig(t *testing.T, messageDir, errorDir string) config.Config {
+	t.Helper()
+	platform := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(platform.Close)
+
+	cfg, err := config.ReadConfig(nil, config.Error)
+	if err != nil {
+		t.Fatalf("Failed to create config: %v", err)
+	}
+	cfg.InstancePort = freePort(t)
+	cfg.Url = platform.URL
+	cfg.RunWaitTime = "1h"
+	cfg.DataDir = t.TempDir()
+	cfg.AdminToken = "secret"
+	cfg.Outbounds = []config.ProcessConfig{{
+		Id:   "out",
+		Type: "FILE",
+		Settings: map[string]any{
+			"message": map[string]any{
+				"path":       messageDir,
+				"extensions": []string{"txt"},
+			},
+			"errorPath": errorDir,
+		},
+	}}
+	return cfg
+}
+
+// runConnector runs a connector with cfg until the test finished and returns
+// it with a function calling its instance port.
+func runConnector(t *testing.T, cfg config.Config) (*connector.Connector, func(method, path, token, body string) (int, string)) {
+	t.Helper()
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c, err := connector.New(logger, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create connector: %v", err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Run(ctx)
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	return c, func(method, path, token, body string) (int, string) {
+		t.Helper()
+		req, err := http.NewRequest(method, fmt.Sprintf("http://127.0.0.1:%d%s", cfg.InstancePort, path), strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("Failed to create request: %v", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		res, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("Failed to call instance port: %v", err)
+		}
+		defer res.Body.Close()
+		data, _ := io.ReadAll(res.Body)
+		return res.StatusCode, string(data)
+	}
+}
+
+func TestAdminApi(t *testing.T) {
+	messageDir := t.TempDir()
+	errorDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(errorDir, "failed.txt"), []byte("failed"), 0644); err != nil {
+		t.Fatalf("Failed to create failed file: %v", err)
+	}
+	_, call := runConnector(t, testConfig(t, messageDir, errorDir))
+
+	if status, _ := call(http.MethodGet, "/api/transports", "", ""); status != http.StatusUnauthorized {
+		t.Errorf("Expected status %d without token, got: %d", http.StatusUnauthorized, status)
+	}
+	if status, _ := call(http.MethodGet, "/api/transports", "wrong", ""); status != http.StatusUnauthorized {
+		t.Errorf("Expected status %d with wrong token, got: %d", http.StatusUnauthorized, status)
+	}
+
+	if status, body := call(http.MethodGet, "/metrics", "", ""); status != http.StatusOK || !strings.Contains(body, "go_goroutines") {
+		t.Errorf("Expected metrics without token, got: %d", status)
+	}
+
+	status, body := call(http.MethodGet, "/api/transports", "secret", "")
+	if status != http.StatusOK {
+		t.Fatalf("Expected status %d, got: %d", http.StatusOK, status)
+	}
+	var transports []struct {
+		Kind     string `json:"kind"`
+		ConfigId string `json:"configId"`
+		Paused   bool   `json:"paused"`
+	}
+	if err := json.Unmarshal([]byte(body), &transports); err != nil {
+		t.Fatalf("Failed to decode transports: %v", err)
+	}
+	if len(transports) != 1 || transports[0].Kind != "outbound" || transports[0].ConfigId != "out" {
+		t.Errorf("Expected outbound transport out, got: %s", body)
+	}
+
+	if status, body := call(http.MethodPost, "/api/transports/outbound/out/pause", "secret", ""); status != http.StatusOK || !strings.Contains(body, `"paused":true`) {
+		t.Errorf("Expected paused transport, got: %d %s", status, body)
+	}
+	if status, _ := call(http.MethodPost, "/api/transports/outbound/out/run", "secret", ""); status != http.StatusConflict {
+		t.Errorf("Expected status %d for run of paused transport, got: %d", http.StatusConflict, status)
+	}
+	if status, _ := call(http.MethodPost, "/api/transports/outbound/out/resume", "secret", ""); status != http.StatusOK {
+		t.Errorf("Expected status %d, got: %d", http.StatusOK, status)
+	}
+	if status, _ := call(http.MethodPost, "/api/transports/inbound/out/run", "secret", ""); status != http.StatusNotFound {
+		t.Errorf("Expected status %d for unknown transport, got: %d", http.StatusNotFound, status)
+	}
+
+	status, body = call(http.MethodGet, "/api/errors", "secret", "")
+	if status != http.StatusOK || !strings.Contains(body, `"name":"failed.txt"`) {
+		t.Errorf("Expected failed.txt within errors, got: %d %s", status, body)
+	}
+	if status, _ := call(http.MethodPost, "/api/errors/out/failed.txt/requeue", "secret", ""); status != http.StatusNoContent {
+		t.Errorf("Expected status %d, got: %d", http.StatusNoContent, status)
+	}
+	if _, err := os.Stat(filepath.Join(messageDir, "failed.txt")); err != nil {
+		t.Errorf("Expected requeued file within message folder, got: %v", err)
+	}
+	if status, _ := call(http.MethodPost, "/api/errors/out/failed.txt/requeue", "secret", ""); status != http.StatusNotFound {
+		t.Errorf("Expected status %d for missing file, got: %d", http.StatusNotFound, status)
+	}
+
+	defer log.SetLevel(log.Level())
+	if status, _ := call(http.MethodPut, "/api/log/level", "secret", `{"level":"DEBUG"}`); status != http.StatusOK {
+		t.Errorf("Expected status %d, got: %d", http.StatusOK, status)
+	}
+	if log.Level() != slog.LevelDebug {
+		t.Errorf("Expected log level %s, got: %s", slog.LevelDebug, log.Level())
+	}
+	if status, _ := call(http.MethodPut, "/api/log/level", "secret", `{"level":"LOUD"}`); status != http.StatusBadRequest {
+		t.Errorf("Expected status %d for invalid level, got: %d", http.StatusBadRequest, status)
+	}
+}
+
+func TestAdminErrorsPerTransport(t *testing.T) {
+	errorDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(errorDir, "failed.txt"), []byte("failed"), 0644); err != nil {
+		t.Fatalf("Failed to create failed file: %v", err)
+	}
+	brokenErrorDir := t.TempDir()
+	cfg := testConfig(t, t.TempDir(), errorDir)
+	cfg.Outbounds = append(cfg.Outbounds, config.ProcessConfig{
+		Id:   "attachments",
+		Type: "FILE",
+		Settings: map[string]any{
+			"attachment": map[string]any{
+				"path":       t.TempDir(),
+				"extensions": []string{"pdf"},
+			},
+		},
+	}, config.ProcessConfig{
+		Id:   "broken",
+		Type: "FILE",
+		Settings: map[string]any{
+			"message": map[string]any{
+				"path":       t.TempDir(),
+				"extensions": []string{"txt"},
+			},
+			"errorPath": brokenErrorDir,
+		},
+	})
+	_, call := runConnector(t, cfg)
+	if err := os.Remove(brokenErrorDir); err != nil {
+		t.Fatalf("Failed to remove error folder: %v", err)
+	}
+
+	status, body := call(http.MethodGet, "/api/errors", "secret", "")
+	if status != http.StatusOK {
+		t.Fatalf("Expected status %d, got: %d %s", http.StatusOK, status, body)
+	}
+	var failed []struct {
+		ConfigId string `json:"configId"`
+		Name     string `json:"name"`
+		Error    string `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(body), &failed); err != nil {
+		t.Fatalf("Failed to decode errors: %v", err)
+	}
+	if len(failed) != 2 {
+		t.Fatalf("Expected 2 entries, got: %s", body)
+	}
+	for _, entry := range failed {
+		switch entry.ConfigId {
+		case "out":
+			if entry.Name != "failed.txt" || entry.Error != "" {
+				t.Errorf("Expected failed.txt of out, got: %+v", entry)
+			}
+		case "broken":
+			if entry.Error == "" {
+				t.Errorf("Expected error for broken, got: %+v", entry)
+			}
+		default:
+			t.Errorf("Unexpected entry: %+v", entry)
+		}
+	}
+}
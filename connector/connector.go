@@ -2,42 +2,78 @@ package connector
 
 import (
 	"context"
-	"crypto/rand"
-	"encoding/hex"
 	"fmt"
 	"io"
 	"log/slog"
-	"mime"
 	"net"
-	"net/http"
-	"net/url"
-	"strings"
+	"slices"
+	"sync"
 	"time"
 
 	"github.com/myopenfactory/edi-connector/v2/config"
 	"github.com/myopenfactory/edi-connector/v2/credentials"
+	"github.com/myopenfactory/edi-connector/v2/metrics"
 	"github.com/myopenfactory/edi-connector/v2/platform"
+	"github.com/myopenfactory/edi-connector/v2/schedule"
+	"github.com/myopenfactory/edi-connector/v2/state"
+	"github.com/myopenfactory/edi-connector/v2/tracing"
 	"github.com/myopenfactory/edi-connector/v2/transport"
-	"github.com/myopenfactory/edi-connector/v2/transport/file"
+
+	// builtin transports
+	_ "github.com/myopenfactory/edi-connector/v2/transport/file"
+	_ "github.com/myopenfactory/edi-connector/v2/transport/plugin"
+	_ "github.com/myopenfactory/edi-connector/v2/transport/sftp"
 )
 
 const defaultInstancePort = 9643
 
+const defaultMaxDeliveryAttempts = 5
+
+const (
+	defaultMaxConcurrency  = 4
+	defaultTransferTimeout = 15 * time.Second
+)
+
+// downloadAttempts is the number of downloads of a transmission failing hash verification before it gets rejected.
+const downloadAttempts = 3
+
 // Config configures variables for the client
 type Connector struct {
 	logger      *slog.Logger
 	runWaitTime time.Duration
 
-	// transports
-	inbounds  []transport.InboundTransport
-	outbounds []transport.OutboundTransport
-
-	platformClient *platform.Client
-	listener       net.Listener
+	platformClient      *platform.Client
+	credManager         credentials.CredManager
+	listener            net.Listener
+	metricsListener     net.Listener
+	store               *state.Store
+	maxDeliveryAttempts int
+	duplicateWindow     time.Duration
+	maxConcurrency      int
+	transferTimeout     time.Duration
+	adminToken          string
+	shutdownTracing     func(context.Context) error
+	// dryRun only logs the transfers instead of executing them
+	dryRun bool
+
+	// mu guards the workers and the state of a running connector. Each worker
+	// processes a single transport.
+	mu      sync.RWMutex
+	workers []*worker
+	config  config.Config
+	runCtx  context.Context
+	slots   chan struct{}
+	wg      sync.WaitGroup
+	// reloadMu serializes reloads of the config
+	reloadMu sync.Mutex
 }
 
 // New creates client with given options
 func New(logger *slog.Logger, cfg config.Config) (*Connector, error) {
+	credManager, err := credentials.NewFromConfig(cfg.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credentials: %w", err)
+	}
 	port := cfg.InstancePort
 	if port == 0 {
 		port = defaultInstancePort
@@ -46,7 +82,15 @@ func New(logger *slog.Logger, cfg config.Config) (*Connector, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to listen on port %d: %w", port, err)
 	}
-	platformClient, err := platform.NewClient(cfg.Url, cfg.CAFile, credentials.NewDefaultCredManager(), cfg.Proxy)
+	var metricsListener net.Listener
+	if cfg.MetricsAddress != "" {
+		metricsListener, err = net.Listen("tcp", cfg.MetricsAddress)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("failed to listen on metrics address %s: %w", cfg.MetricsAddress, err)
+		}
+	}
+	platformClient, err := platform.NewClient(logger, cfg.Url, cfg.CAFile, credManager, cfg.Proxy)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create platform client: %w", err)
 	}
@@ -55,265 +99,252 @@ func New(logger *slog.Logger, cfg config.Config) (*Connector, error) {
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse runWaitTime duration: %w", err)
 	}
-	c := &Connector{
-		logger:         logger,
-		runWaitTime:    d,
-		platformClient: platformClient,
-		listener:       listener,
-	}
-
-	logger.Info("Configured connector", "runWaitTime", c.runWaitTime)
-
-	c.inbounds = []transport.InboundTransport{}
-	c.outbounds = []transport.OutboundTransport{}
-	for _, pc := range cfg.Outbounds {
-		switch pc.Type {
-		case "FILE":
-			outbound, err := file.NewOutboundTransport(c.logger, pc.Id, pc.AuthName, pc.Settings)
-			if err != nil {
-				return nil, fmt.Errorf("failed to load transport: processid: %v: %w", pc.Id, err)
-			}
-			c.outbounds = append(c.outbounds, outbound)
+	var duplicateWindow time.Duration
+	if cfg.DuplicateWindow != "" {
+		duplicateWindow, err = time.ParseDuration(cfg.DuplicateWindow)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse duplicateWindow duration: %w", err)
 		}
 	}
-	for _, pc := range cfg.Inbounds {
-		switch pc.Type {
-		case "FILE":
-			inbound, err := file.NewInboundTransport(c.logger, pc.Id, pc.AuthName, pc.Settings)
-			if err != nil {
-				return nil, fmt.Errorf("failed to load transport: processid: %v: %w", pc.Id, err)
-			}
-			c.inbounds = append(c.inbounds, inbound)
+	transferTimeout := defaultTransferTimeout
+	if cfg.TransferTimeout != "" {
+		transferTimeout, err = time.ParseDuration(cfg.TransferTimeout)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse transferTimeout duration: %w", err)
 		}
 	}
-
-	return c, nil
-}
-
-// Runs client until context is closed
-func (c *Connector) Run(rootCtx context.Context) error {
-	defer c.listener.Close()
-	ticker := time.NewTicker(c.runWaitTime)
-	for {
-		select {
-		case <-ticker.C:
-			for _, transport := range c.outbounds {
-				ctx, cancel := context.WithTimeout(rootCtx, 15*time.Second)
-				if err := c.outboundAttachments(ctx, transport); err != nil {
-					c.logger.Error("error processing outbound attachment", "error", err)
-					cancel()
-					continue
-				}
-				cancel()
-
-				ctx, cancel = context.WithTimeout(rootCtx, 15*time.Second)
-				if err := c.outboundMessages(ctx, transport); err != nil {
-					c.logger.Error("error processing outbound message", "error", err)
-					cancel()
-					continue
-				}
-				cancel()
-			}
-
-			for _, transport := range c.inbounds {
-				ctx, cancel := context.WithTimeout(rootCtx, 15*time.Second)
-				if err := c.inboundMessages(ctx, transport); err != nil {
-					c.logger.Error("error processing inbound transmissions", "configId", transport.ConfigId(), "authName", transport.AuthName(), "error", err)
-					cancel()
-					continue
-				}
-				cancel()
-			}
-		case <-rootCtx.Done():
-			return nil
-		}
+	store, err := state.Open(cfg.DataDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open state store: %w", err)
 	}
-}
 
-func (c *Connector) outboundMessages(ctx context.Context, outbound transport.OutboundTransport) error {
-	messages, err := outbound.ListMessages(ctx)
+	token, err := adminToken(cfg)
 	if err != nil {
-		return fmt.Errorf("failed to list messages")
-	}
-
-	finalizer, isFinalizer := outbound.(transport.Finalizer)
-	for _, msg := range messages {
-		ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
-		defer cancel()
-		if err := c.platformClient.AddTransmission(ctx, outbound.ConfigId(), outbound.AuthName(), msg.Content); err != nil {
-			if isFinalizer {
-				finalizerErr := finalizer.Finalize(ctx, msg, err)
-				if finalizerErr != nil {
-					return fmt.Errorf("could not finalize message %s: %w", msg.Id, finalizerErr)
-				}
-			}
-			return fmt.Errorf("failed tu upload message %s: %w", msg.Id, err)
-		}
-		if isFinalizer {
-			err = finalizer.Finalize(ctx, msg, nil)
-			if err != nil {
-				return fmt.Errorf("could not finalize message %s: %w", msg.Id, err)
-			}
-		}
-		cancel()
+		return nil, err
 	}
 
-	return nil
-}
+	maxDeliveryAttempts := cfg.MaxDeliveryAttempts
+	if maxDeliveryAttempts <= 0 {
+		maxDeliveryAttempts = defaultMaxDeliveryAttempts
+	}
+	maxConcurrency := cfg.MaxConcurrency
+	if maxConcurrency <= 0 {
+		maxConcurrency = defaultMaxConcurrency
+	}
+	c := &Connector{
+		logger:              logger,
+		runWaitTime:         d,
+		platformClient:      platformClient,
+		credManager:         credManager,
+		listener:            listener,
+		metricsListener:     metricsListener,
+		store:               store,
+		maxDeliveryAttempts: maxDeliveryAttempts,
+		duplicateWindow:     duplicateWindow,
+		maxConcurrency:      maxConcurrency,
+		transferTimeout:     transferTimeout,
+		adminToken:          token,
+		config:              cfg,
+		dryRun:              cfg.DryRun,
+		slots:               make(chan struct{}, maxConcurrency),
+	}
 
-func (c *Connector) outboundAttachments(ctx context.Context, outbound transport.OutboundTransport) error {
-	attachments, err := outbound.ListAttachments(ctx)
-	if err != nil {
-		c.logger.Error("error while reading attachment: %v", "error", err)
+	logger.Info("Configured connector", "runWaitTime", c.runWaitTime, "dataDir", cfg.DataDir, "maxDeliveryAttempts", c.maxDeliveryAttempts, "duplicateWindow", c.duplicateWindow, "maxConcurrency", c.maxConcurrency, "transferTimeout", c.transferTimeout)
+	if c.dryRun {
+		logger.Warn("dry run, transfers are only logged")
+	}
+	if c.adminToken == "" {
+		logger.Warn("admin api disabled, neither an admin token nor a data dir to store one is configured")
 	}
 
-	finalizer, isFinalizer := outbound.(transport.Finalizer)
-	for _, attachment := range attachments {
-		if err := c.platformClient.AddAttachment(ctx, attachment.Content, attachment.Id, outbound.AuthName()); err != nil {
-			if isFinalizer {
-				finalizerErr := finalizer.Finalize(ctx, attachment, err)
-				if finalizerErr != nil {
-					return fmt.Errorf("could not finalize attachment %s: %w", attachment.Id, finalizerErr)
-				}
-			}
-			return fmt.Errorf("failed to upload attachment for %s: %w", attachment.Id, err)
+	for _, pc := range cfg.Outbounds {
+		w, err := c.newProcess(metrics.Outbound, pc, c.runWaitTime)
+		if err != nil {
+			return nil, err
 		}
-		if isFinalizer {
-			err = finalizer.Finalize(ctx, attachment, nil)
-			if err != nil {
-				return fmt.Errorf("could not finalize attachment %s: %w", attachment.Id, err)
-			}
+		c.workers = append(c.workers, w)
+	}
+	for _, pc := range cfg.Inbounds {
+		w, err := c.newProcess(metrics.Inbound, pc, c.runWaitTime)
+		if err != nil {
+			return nil, err
 		}
+		c.workers = append(c.workers, w)
 	}
-	return nil
-}
 
-func (c *Connector) inboundMessages(ctx context.Context, inbound transport.InboundTransport) error {
-	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
-	defer cancel()
-	transmissions, err := c.platformClient.ListTransmissions(ctx, inbound.ConfigId(), inbound.AuthName())
+	c.shutdownTracing, err = tracing.Setup(context.Background(), cfg.Tracing)
 	if err != nil {
-		return fmt.Errorf("failed to list transmissions: %w", err)
+		return nil, err
+	}
+	if cfg.Tracing.Endpoint != "" {
+		logger.Info("exporting traces", "endpoint", cfg.Tracing.Endpoint)
 	}
-	cancel()
 
-	for _, transmission := range transmissions {
-		if err := c.inboundAttachments(ctx, inbound, transmission); err != nil {
-			return fmt.Errorf("could not process attachment for %s: %w", transmission.Id, err)
-		}
+	return c, nil
+}
 
-		data, err := c.platformClient.DownloadTransmission(transmission, inbound.AuthName())
-		if err != nil {
-			c.logger.Error("failed to download transmission", "error", err)
-			continue
-		}
+// newProcess creates the transport of kind configured by pc and the worker
+// processing it. Processes without their own runWaitTime run every interval.
+func (c *Connector) newProcess(kind string, pc config.ProcessConfig, interval time.Duration) (*worker, error) {
+	runSchedule, concurrency, err := processOptions(pc, interval)
+	if err != nil {
+		return nil, err
+	}
 
-		ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
-		defer cancel()
-		statusMsg, err := inbound.ProcessMessage(ctx, transport.Object{
-			Id:       transmission.Id,
-			Content:  data,
-			Metadata: transmission.Metadata,
-		})
+	if kind == metrics.Outbound {
+		outbound, err := transport.NewOutbound(c.logger, pc, c.credManager)
 		if err != nil {
-			return fmt.Errorf("failed to process message: %s", transmission.Id)
+			return nil, fmt.Errorf("failed to load transport: processid: %v: %w", pc.Id, err)
 		}
-		cancel()
+		return newWorker(kind, pc, outbound, runSchedule, func(ctx context.Context) error {
+			return c.processOutbound(ctx, outbound, concurrency)
+		}), nil
+	}
+	inbound, err := transport.NewInbound(c.logger, pc, c.credManager)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load transport: processid: %v: %w", pc.Id, err)
+	}
+	return newWorker(kind, pc, inbound, runSchedule, func(ctx context.Context) error {
+		return c.inboundMessages(ctx, inbound, concurrency)
+	}), nil
+}
 
-		ctx, cancel = context.WithTimeout(ctx, 15*time.Second)
-		defer cancel()
-		err = c.platformClient.ConfirmTransmission(ctx, transmission.Id, inbound.AuthName(), statusMsg)
+// processOptions returns the schedule and number of concurrent transfers of pc.
+func processOptions(pc config.ProcessConfig, interval time.Duration) (*schedule.Schedule, int, error) {
+	if pc.RunWaitTime != "" {
+		d, err := time.ParseDuration(pc.RunWaitTime)
 		if err != nil {
-			return fmt.Errorf("could not confirm inbound transmission %s: %w", transmission.Id, err)
+			return nil, 0, fmt.Errorf("failed to parse runWaitTime duration of process %s: %w", pc.Id, err)
 		}
-		cancel()
+		interval = d
 	}
-	return nil
+	s, err := schedule.New(interval, pc.Schedule)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid schedule of process %s: %w", pc.Id, err)
+	}
+	return s, max(pc.Concurrency, 1), nil
 }
 
-func (c *Connector) inboundAttachments(ctx context.Context, inbound transport.InboundTransport, transmission platform.Transmission) error {
-	if len(transmission.MessageIds) == 0 {
-		return nil
+// Runs client until context is closed
+func (c *Connector) Run(rootCtx context.Context) error {
+	defer c.serve(rootCtx, "admin api", c.listener, c.adminHandler())()
+	if c.metricsListener != nil {
+		defer c.serve(rootCtx, "metrics", c.metricsListener, c.metricsHandler())()
 	}
-	ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
-	defer cancel()
-	for _, messageId := range transmission.MessageIds {
-		c.logger.Debug("processing attachments for message", "messageId", messageId)
-		attachments, err := c.platformClient.ListMessageAttachments(ctx, messageId, inbound.AuthName())
-		if err != nil {
-			return fmt.Errorf("failed to list message attachments for %s: %w", messageId, err)
-		}
-
-		for _, attachment := range attachments {
-			c.logger.Debug("found attachment to handle", "attachmentUrl", attachment.Url)
-			if !inbound.HandleAttachment(attachment.Url) {
-				return nil
-			}
-
-			data, filename, err := c.downloadAttachment(attachment.Url)
-			if err != nil {
-				return fmt.Errorf("failed to download attachment for %s: %w", messageId, err)
-			}
+	defer func() { c.closeTransports(c.snapshot()) }()
+	defer c.flushTraces(rootCtx)
+	c.resume(rootCtx)
+
+	defer c.wg.Wait()
+	c.mu.Lock()
+	c.runCtx = rootCtx
+	for _, w := range c.workers {
+		c.start(rootCtx, w)
+	}
+	pruneInterval := c.runWaitTime
+	c.mu.Unlock()
 
-			ctx, cancel := context.WithTimeout(ctx, 15*time.Second)
-			defer cancel()
-			if err := inbound.ProcessAttachment(ctx, transport.Object{
-				Id:      generateId(),
-				Content: data,
-				Metadata: map[string]string{
-					"filename": filename,
-				},
-			}); err != nil {
-				return fmt.Errorf("error processing attachment: %w", err)
-			}
+	ticker := time.NewTicker(pruneInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			c.pruneJournal()
+		case <-rootCtx.Done():
+			return nil
 		}
 	}
-	return nil
 }
 
-func generateId() string {
-	bytes := make([]byte, 16)
-	if _, err := rand.Read(bytes); err != nil {
-		panic(err)
+// flushTraces exports the pending traces, even if ctx is done.
+func (c *Connector) flushTraces(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := c.shutdownTracing(ctx); err != nil {
+		c.logger.Error("failed to flush traces", "error", err)
 	}
-	return hex.EncodeToString(bytes)
 }
 
-func (c *Connector) downloadAttachment(attachmentUrl string) ([]byte, string, error) {
-	if attachmentUrl == "" {
-		return nil, "", fmt.Errorf("attachment url couldn't be empty")
+// processOutbound uploads the attachments and messages of outbound.
+func (c *Connector) processOutbound(ctx context.Context, outbound transport.OutboundTransport, concurrency int) error {
+	if err := c.outboundAttachments(ctx, outbound, concurrency); err != nil {
+		return fmt.Errorf("error processing outbound attachment: %w", err)
 	}
-	resp, err := http.Get(attachmentUrl)
-	if err != nil {
-		return nil, "", fmt.Errorf("error while loading attachment with url %q: %w", attachmentUrl, err)
+	if err := c.outboundMessages(ctx, outbound, concurrency); err != nil {
+		return fmt.Errorf("error processing outbound message: %w", err)
 	}
-	defer resp.Body.Close()
+	return nil
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return nil, "", fmt.Errorf("error bad response for attachment %q: %w", attachmentUrl, err)
+// start runs w until ctx is done or w is stopped. If the transport of w
+// implements transport.Notifier, w is woken on its notifications. The caller
+// must hold c.mu.
+func (c *Connector) start(ctx context.Context, w *worker) {
+	done := make(chan struct{})
+	w.mu.Lock()
+	w.done = done
+	w.mu.Unlock()
+	c.wg.Go(func() {
+		defer close(done)
+		c.work(ctx, w)
+	})
+
+	notifier, ok := w.transport.(transport.Notifier)
+	if !ok || notifier.Notify() == nil {
+		return
 	}
+	c.wg.Go(func() {
+		for {
+			select {
+			case <-notifier.Notify():
+				c.logger.Debug("processing "+w.kind+" transport on notification", "configId", w.configId)
+				w.wake()
+			case <-w.stop:
+				return
+			case <-ctx.Done():
+				return
+			}
+		}
+	})
+}
 
-	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
-	if err != nil {
-		return nil, "", fmt.Errorf("invalid content-disposition on attachment %q: %w", attachmentUrl, err)
-	}
+// snapshot returns the current workers.
+func (c *Connector) snapshot() []*worker {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return slices.Clone(c.workers)
+}
 
-	data, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return nil, "", fmt.Errorf("error while writing response data for attachment %q: %w", attachmentUrl, err)
+// outbounds returns the current outbound transports.
+func (c *Connector) outbounds() []transport.OutboundTransport {
+	var outbounds []transport.OutboundTransport
+	for _, w := range c.snapshot() {
+		if outbound, ok := w.transport.(transport.OutboundTransport); ok && w.kind == metrics.Outbound {
+			outbounds = append(outbounds, outbound)
+		}
 	}
+	return outbounds
+}
 
-	filename, ok := params["filename"]
-	if !ok {
-		url, err := url.Parse(attachmentUrl)
-		if err != nil {
-			return nil, "", fmt.Errorf("failed to parse attachment url: %w", err)
-		}
-		slashIndex := strings.LastIndex(url.Path, "/")
-		if slashIndex != -1 {
-			filename = url.Path[slashIndex+1:]
+// inbounds returns the current inbound transports.
+func (c *Connector) inbounds() []transport.InboundTransport {
+	var inbounds []transport.InboundTransport
+	for _, w := range c.snapshot() {
+		if inbound, ok := w.transport.(transport.InboundTransport); ok && w.kind == metrics.Inbound {
+			inbounds = append(inbounds, inbound)
 		}
 	}
+	return inbounds
+}
 
-	return data, filename, nil
+// closeTransports releases resources like connections or plugin processes
+// held by the transports of workers implementing io.Closer.
+func (c *Connector) closeTransports(workers []*worker) {
+	for _, w := range workers {
+		if closer, ok := w.transport.(io.Closer); ok {
+			if err := closer.Close(); err != nil {
+				c.logger.Error("failed to close "+w.kind+" transport", "configId", w.configId, "error", err)
+			}
+		}
+	}
 }
@@ -0,0 +1,66 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/myopenfactory/edi-connector/v2/metrics"
+	"github.com/myopenfactory/edi-connector/v2/platform"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+)
+
+// dryRunUpload logs the upload of obj of outbound instead of uploading and
+// finalizing it.
+func (c *Connector) dryRunUpload(ctx context.Context, outbound transport.OutboundTransport, kind string, obj transport.Object, hash string) {
+	size := obj.Size
+	if obj.Open == nil {
+		size = int64(len(obj.Content))
+	}
+	c.logger.Info("dry run: would upload "+kind, "configId", outbound.ConfigId(), "authName", outbound.AuthName(), "id", obj.Id, "size", size, "hash", hash)
+	tallyTransfer(ctx, metrics.Outbound)
+}
+
+// dryRunTransmission logs the destinations of transmission and its
+// attachments reported by inbound. The attachments are downloaded to learn
+// their filename, nothing is delivered, journaled or confirmed.
+func (c *Connector) dryRunTransmission(ctx context.Context, inbound transport.InboundTransport, transmission platform.Transmission) error {
+	for _, messageId := range transmission.MessageIds {
+		listCtx, cancel := context.WithTimeout(ctx, c.transferTimeout)
+		attachments, err := c.platformClient.ListMessageAttachments(listCtx, messageId, inbound.AuthName())
+		cancel()
+		if err != nil {
+			countFailure(ctx, metrics.PhaseList, inbound.ConfigId(), inbound.AuthName())
+			return fmt.Errorf("failed to list message attachments for %s: %w", messageId, err)
+		}
+		for _, attachment := range attachments {
+			if !inbound.HandleAttachment(attachment.Url) {
+				continue
+			}
+			downloadCtx, cancel := context.WithTimeout(ctx, c.transferTimeout)
+			filename, size, err := c.downloadAttachment(downloadCtx, attachment.Url, io.Discard)
+			cancel()
+			if err != nil {
+				countFailure(ctx, metrics.PhaseDownload, inbound.ConfigId(), inbound.AuthName())
+				return fmt.Errorf("failed to download attachment for %s: %w", messageId, err)
+			}
+			obj := transport.Object{Id: generateId(), Metadata: map[string]string{"filename": filename}}
+			c.logger.Info("dry run: would deliver attachment", "configId", inbound.ConfigId(), "transmissionId", transmission.Id, "url", attachment.Url, "size", size, "target", target(inbound, obj, true))
+			tallyTransfer(ctx, metrics.Inbound)
+		}
+	}
+
+	obj := transport.Object{Id: transmission.Id, Metadata: transmission.Metadata}
+	c.logger.Info("dry run: would deliver and confirm transmission", "configId", inbound.ConfigId(), "authName", inbound.AuthName(), "transmissionId", transmission.Id, "target", target(inbound, obj, false))
+	tallyTransfer(ctx, metrics.Inbound)
+	return nil
+}
+
+// target returns the destination of obj reported by inbound, if it
+// implements transport.Targeter.
+func target(inbound transport.InboundTransport, obj transport.Object, attachment bool) string {
+	if targeter, ok := inbound.(transport.Targeter); ok {
+		return targeter.Target(obj, attachment)
+	}
+	return "unknown"
+}
@@ -0,0 +1,145 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+)
+
+// readinessTimeout limits the checks of a readiness request.
+const readinessTimeout = 5 * time.Second
+
+// health is the body of the health and readiness responses.
+type health struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks,omitempty"`
+}
+
+func (c *Connector) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, health{Status: "ok"})
+}
+
+func (c *Connector) handleReady(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessTimeout)
+	defer cancel()
+	checks, ready := c.ready(ctx)
+	if !ready {
+		writeJSON(w, http.StatusServiceUnavailable, health{Status: "not ready", Checks: checks})
+		return
+	}
+	writeJSON(w, http.StatusOK, health{Status: "ready", Checks: checks})
+}
+
+// ready checks the credentials of every authName and the credentials used by
+// the transports themselves can be resolved, the platform is reachable and
+// the folders of all transports are accessible.
+// The config is loaded once the connector exists. It returns the result of
+// each check and whether all checks passed.
+func (c *Connector) ready(ctx context.Context) (map[string]string, bool) {
+	checks := map[string]func(context.Context) error{
+		"config":   func(context.Context) error { return nil },
+		"platform": c.platformClient.Ping,
+	}
+	for _, credentialName := range c.credentialNames() {
+		name := credentialName
+		if name == "" {
+			name = "default"
+		}
+		checks["credentials "+name] = func(context.Context) error {
+			_, err := c.credManager.GetCredential(credentialName)
+			return err
+		}
+	}
+	for _, outbound := range c.outbounds() {
+		if checker, ok := outbound.(transport.Checker); ok {
+			checks["outbound "+outbound.ConfigId()] = checker.Check
+		}
+	}
+	for _, inbound := range c.inbounds() {
+		if checker, ok := inbound.(transport.Checker); ok {
+			checks["inbound "+inbound.ConfigId()] = checker.Check
+		}
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[string]string, len(checks))
+	ready := true
+	for name, check := range checks {
+		wg.Go(func() {
+			result := "ok"
+			if err := check(ctx); err != nil {
+				result = err.Error()
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			results[name] = result
+			if result != "ok" {
+				ready = false
+			}
+		})
+	}
+	wg.Wait()
+	return results, ready
+}
+
+// credentialNames returns the distinct authNames of all transports and the
+// names of the credentials used by the transports themselves.
+func (c *Connector) credentialNames() []string {
+	var names []string
+	seen := make(map[string]bool)
+	add := func(name string) {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	addTransport := func(t transport.ConfigInfo) {
+		add(t.AuthName())
+		if user, ok := t.(transport.CredentialUser); ok {
+			for _, name := range user.CredentialNames() {
+				add(name)
+			}
+		}
+	}
+	for _, outbound := range c.outbounds() {
+		addTransport(outbound)
+	}
+	for _, inbound := range c.inbounds() {
+		addTransport(inbound)
+	}
+	return names
+}
+
+// CheckHealth queries the health and readiness endpoints of the connector
+// running with cfg on this machine.
+func CheckHealth(ctx context.Context, cfg config.Config) error {
+	port := cfg.InstancePort
+	if port == 0 {
+		port = defaultInstancePort
+	}
+	client := &http.Client{Timeout: readinessTimeout + time.Second}
+	for _, path := range []string{"/healthz", "/readyz"} {
+		req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("http://127.0.0.1:%d%s", port, path), nil)
+		if err != nil {
+			return fmt.Errorf("failed to create request: %w", err)
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return fmt.Errorf("failed to query %s: %w", path, err)
+		}
+		body, _ := io.ReadAll(io.LimitReader(res.Body, 64*1024))
+		res.Body.Close()
+		if res.StatusCode != http.StatusOK {
+			return fmt.Errorf("%s returned %s: %s", path, res.Status, strings.TrimSpace(string(body)))
+		}
+	}
+	return nil
+}
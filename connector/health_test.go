@@ -0,0 +1,77 @@
+package connector_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/connector"
+)
+
+func TestHealth(t *testing.T) {
+	messageDir := t.TempDir()
+	cfg := testConfig(t, messageDir, t.TempDir())
+	_, call := runConnector(t, cfg)
+
+	if status, _ := call(http.MethodGet, "/healthz", "", ""); status != http.StatusOK {
+		t.Errorf("Expected status %d, got: %d", http.StatusOK, status)
+	}
+
+	t.Setenv("EDI_CONNECTOR", "")
+	os.Unsetenv("EDI_CONNECTOR")
+	status, body := call(http.MethodGet, "/readyz", "", "")
+	if status != http.StatusServiceUnavailable || !strings.Contains(body, `"credentials default"`) {
+		t.Errorf("Expected not ready without credentials, got: %d %s", status, body)
+	}
+	if err := connector.CheckHealth(context.Background(), cfg); err == nil {
+		t.Errorf("Expected health check to fail without credentials")
+	}
+
+	t.Setenv("EDI_CONNECTOR", "user:password")
+	status, body = call(http.MethodGet, "/readyz", "", "")
+	if status != http.StatusOK {
+		t.Errorf("Expected ready, got: %d %s", status, body)
+	}
+	if err := connector.CheckHealth(context.Background(), cfg); err != nil {
+		t.Errorf("Expected health check to succeed, got: %v", err)
+	}
+
+	if err := os.Remove(messageDir); err != nil {
+		t.Fatalf("Failed to remove message folder: %v", err)
+	}
+	status, body = call(http.MethodGet, "/readyz", "", "")
+	if status != http.StatusServiceUnavailable || !strings.Contains(body, `"outbound out"`) {
+		t.Errorf("Expected not ready without message folder, got: %d %s", status, body)
+	}
+}
+
+func TestReadySftpCredential(t *testing.T) {
+	t.Setenv("EDI_CONNECTOR", "user:password")
+	cfg := testConfig(t, t.TempDir(), t.TempDir())
+	cfg.Inbounds = []config.ProcessConfig{{
+		Id:   "in",
+		Type: "SFTP",
+		Settings: map[string]any{
+			"host":                  "127.0.0.1",
+			"credentialName":        "sftp",
+			"insecureIgnoreHostKey": true,
+			"path":                  "/in",
+		},
+	}}
+	_, call := runConnector(t, cfg)
+
+	status, body := call(http.MethodGet, "/readyz", "", "")
+	var result struct {
+		Checks map[string]string `json:"checks"`
+	}
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		t.Fatalf("Failed to decode readiness %q: %v", body, err)
+	}
+	if status != http.StatusServiceUnavailable || result.Checks["credentials sftp"] == "" || result.Checks["credentials sftp"] == "ok" {
+		t.Errorf("Expected not ready without sftp credential, got: %d %s", status, body)
+	}
+}
@@ -0,0 +1,355 @@
+package connector
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/myopenfactory/edi-connector/v2/metrics"
+	"github.com/myopenfactory/edi-connector/v2/platform"
+	"github.com/myopenfactory/edi-connector/v2/state"
+	"github.com/myopenfactory/edi-connector/v2/tracing"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func (c *Connector) inboundMessages(ctx context.Context, inbound transport.InboundTransport, concurrency int) error {
+	listCtx, cancel := context.WithTimeout(ctx, c.transferTimeout)
+	defer cancel()
+	listCtx, listSpan := tracing.Start(listCtx, "ListTransmissions", trace.WithAttributes(transportAttributes(inbound)...))
+	transmissions, err := c.platformClient.ListTransmissions(listCtx, inbound.ConfigId(), inbound.AuthName())
+	tracing.End(listSpan, err)
+	if err != nil {
+		countFailure(ctx, metrics.PhaseList, inbound.ConfigId(), inbound.AuthName())
+		return fmt.Errorf("failed to list transmissions: %w", err)
+	}
+
+	return forEach(ctx, transmissions, concurrency, func(ctx context.Context, transmission platform.Transmission) error {
+		ctx, span := tracing.StartRoot(ctx, "inbound transmission", listSpan.SpanContext(),
+			append(transportAttributes(inbound), tracing.TransmissionId.String(transmission.Id))...)
+		var err error
+		if c.dryRun {
+			err = c.dryRunTransmission(ctx, inbound, transmission)
+		} else {
+			err = c.inboundTransmission(ctx, inbound, transmission)
+		}
+		tracing.End(span, err)
+		return err
+	})
+}
+
+// inboundTransmission delivers a single transmission and confirms it. Each
+// step is journaled, a transmission delivered by a previous run is only
+// confirmed and not delivered again.
+func (c *Connector) inboundTransmission(ctx context.Context, inbound transport.InboundTransport, transmission platform.Transmission) error {
+	key := state.InboundKey(inbound.ConfigId(), transmission.Id)
+	record, _ := c.store.Get(key)
+	switch record.Phase {
+	case state.PhaseConfirmed:
+		c.logger.Info("transmission already confirmed, skipping", "configId", inbound.ConfigId(), "transmissionId", transmission.Id)
+		return nil
+	case state.PhaseDelivered:
+		c.logger.Info("transmission already delivered, resuming confirmation", "configId", inbound.ConfigId(), "transmissionId", transmission.Id)
+		return c.confirmTransmission(ctx, key, record)
+	case state.PhaseRejected:
+		c.logger.Info("transmission already rejected, resuming confirmation as failed", "configId", inbound.ConfigId(), "transmissionId", transmission.Id)
+		return c.confirmRejection(ctx, key, record)
+	}
+
+	record, err := c.store.Update(key, func(r *state.Record) {
+		r.Direction = state.DirectionInbound
+		r.ConfigId = inbound.ConfigId()
+		r.AuthName = inbound.AuthName()
+		r.ObjectId = transmission.Id
+		r.Metadata = transmission.Metadata
+		r.Phase = state.PhaseListed
+	})
+	if err != nil {
+		return fmt.Errorf("failed to journal transmission %s: %w", transmission.Id, err)
+	}
+
+	attachmentsCtx, span := tracing.Start(ctx, "attachments")
+	err = c.inboundAttachments(attachmentsCtx, inbound, transmission, key, record)
+	tracing.End(span, err)
+	if err != nil {
+		return fmt.Errorf("could not process attachment for %s: %w", transmission.Id, err)
+	}
+
+	downloadCtx, span := tracing.Start(ctx, "DownloadTransmission")
+	staged, err := c.downloadTransmission(downloadCtx, inbound, transmission)
+	tracing.End(span, err)
+	if err != nil {
+		countFailure(ctx, metrics.PhaseDownload, inbound.ConfigId(), inbound.AuthName())
+	}
+	if errors.Is(err, platform.ErrHashMismatch) {
+		c.logger.Error("rejecting transmission with invalid hash", "configId", inbound.ConfigId(), "transmissionId", transmission.Id, "error", err)
+		message := fmt.Sprintf("Download failed hash verification after %d attempts: %v", downloadAttempts, err)
+		return c.rejectTransmission(ctx, inbound, transmission, key, message)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to download transmission %s: %w", transmission.Id, err)
+	}
+	defer func() {
+		if err := staged.remove(); err != nil {
+			c.logger.Error("failed to remove staging file", "path", staged.path, "error", err)
+		}
+	}()
+	if _, err := c.store.Update(key, func(r *state.Record) {
+		r.Hash = staged.hash
+		r.Phase = state.PhaseDownloaded
+	}); err != nil {
+		return fmt.Errorf("failed to journal download of %s: %w", transmission.Id, err)
+	}
+
+	processCtx, cancel := context.WithTimeout(ctx, c.transferTimeout)
+	defer cancel()
+	processCtx, span = tracing.Start(processCtx, "ProcessMessage")
+	statusMsg, err := inbound.ProcessMessage(processCtx, staged.object(transmission.Id, transmission.Metadata))
+	tracing.End(span, err)
+	if err != nil {
+		countFailure(ctx, metrics.PhaseProcess, inbound.ConfigId(), inbound.AuthName())
+		return c.deliveryFailed(ctx, inbound, transmission, key, err)
+	}
+	cancel()
+	countTransfer(ctx, metrics.Inbound, metrics.Message, inbound.ConfigId(), inbound.AuthName(), staged.size)
+
+	record, err = c.store.Update(key, func(r *state.Record) {
+		r.Status = statusMsg
+		r.Phase = state.PhaseDelivered
+	})
+	if err != nil {
+		return fmt.Errorf("failed to journal delivery of %s: %w", transmission.Id, err)
+	}
+
+	return c.confirmTransmission(ctx, key, record)
+}
+
+// confirmTransmission confirms the delivered transmission of record with its status.
+func (c *Connector) confirmTransmission(ctx context.Context, key string, record state.Record) error {
+	ctx, cancel := context.WithTimeout(ctx, c.transferTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "ConfirmTransmission")
+	err := c.platformClient.ConfirmTransmission(ctx, record.ObjectId, record.AuthName, record.Status)
+	tracing.End(span, err)
+	if err != nil {
+		countFailure(ctx, metrics.PhaseConfirm, record.ConfigId, record.AuthName)
+		return fmt.Errorf("could not confirm inbound transmission %s: %w", record.ObjectId, err)
+	}
+	if _, err := c.store.Update(key, func(r *state.Record) { r.Phase = state.PhaseConfirmed }); err != nil {
+		c.logger.Error("failed to journal confirmation", "transmissionId", record.ObjectId, "error", err)
+	}
+	return nil
+}
+
+// rejectTransmission journals transmission as rejected with message and
+// confirms it as failed. The rejection is journaled first, so a failed
+// confirmation is sent again instead of delivering the transmission.
+func (c *Connector) rejectTransmission(ctx context.Context, inbound transport.InboundTransport, transmission platform.Transmission, key, message string) error {
+	record, err := c.store.Update(key, func(r *state.Record) {
+		r.LastError = message
+		r.Phase = state.PhaseRejected
+	})
+	if err != nil {
+		return fmt.Errorf("failed to journal rejection of %s: %w", transmission.Id, err)
+	}
+	return c.confirmRejection(ctx, key, record)
+}
+
+// confirmRejection confirms the rejected transmission of record as failed with its last error.
+func (c *Connector) confirmRejection(ctx context.Context, key string, record state.Record) error {
+	ctx, cancel := context.WithTimeout(ctx, c.transferTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "ConfirmTransmission", trace.WithAttributes(attribute.Bool("edi.rejected", true)))
+	err := c.platformClient.ConfirmTransmissionError(ctx, record.ObjectId, record.AuthName, record.LastError)
+	tracing.End(span, err)
+	if err != nil {
+		countFailure(ctx, metrics.PhaseConfirm, record.ConfigId, record.AuthName)
+		return fmt.Errorf("could not confirm failed inbound transmission %s: %w", record.ObjectId, err)
+	}
+	if _, err := c.store.Update(key, func(r *state.Record) { r.Phase = state.PhaseConfirmed }); err != nil {
+		c.logger.Error("failed to journal confirmation", "transmissionId", record.ObjectId, "error", err)
+	}
+	return nil
+}
+
+// deliveryFailed records a failed local delivery of transmission. Once the
+// maximum number of attempts is reached the transmission is confirmed as
+// failed, otherwise it is offered again on the next run. Deliveries
+// interrupted by ctx aren't recorded.
+func (c *Connector) deliveryFailed(ctx context.Context, inbound transport.InboundTransport, transmission platform.Transmission, key string, deliveryErr error) error {
+	if ctx.Err() != nil {
+		// the delivery was interrupted, e.g. by a shutdown, and isn't an attempt
+		return fmt.Errorf("delivery of message %s interrupted: %w", transmission.Id, deliveryErr)
+	}
+	record, err := c.store.Update(key, func(r *state.Record) {
+		r.Attempts++
+		r.LastError = deliveryErr.Error()
+	})
+	if err != nil {
+		return fmt.Errorf("failed to process message %s: %w (could not record attempt: %v)", transmission.Id, deliveryErr, err)
+	}
+	if record.Attempts < c.maxDeliveryAttempts {
+		return fmt.Errorf("failed to process message %s (attempt %d of %d): %w", transmission.Id, record.Attempts, c.maxDeliveryAttempts, deliveryErr)
+	}
+
+	c.logger.Error("giving up delivery of transmission", "configId", inbound.ConfigId(), "transmissionId", transmission.Id, "attempts", record.Attempts, "error", deliveryErr)
+	message := fmt.Sprintf("Delivery failed after %d attempts: %v", record.Attempts, deliveryErr)
+	return c.rejectTransmission(ctx, inbound, transmission, key, message)
+}
+
+// downloadTransmission streams the transmission content into a staging file
+// and retries the download if the content doesn't match the announced hash.
+func (c *Connector) downloadTransmission(ctx context.Context, inbound transport.InboundTransport, transmission platform.Transmission) (stagedFile, error) {
+	var err error
+	for attempt := 1; attempt <= downloadAttempts; attempt++ {
+		var staged stagedFile
+		staged, err = c.receive(inbound, false, func(w io.Writer) (int64, error) {
+			ctx, cancel := context.WithTimeout(ctx, c.transferTimeout)
+			defer cancel()
+			return c.platformClient.DownloadTransmissionTo(ctx, transmission, inbound.AuthName(), w)
+		})
+		if !errors.Is(err, platform.ErrHashMismatch) {
+			return staged, err
+		}
+		c.logger.Warn("downloaded transmission failed hash verification", "transmissionId", transmission.Id, "attempt", attempt, "error", err)
+	}
+	return stagedFile{}, err
+}
+
+// inboundAttachments delivers the attachments of all messages within transmission.
+// Attachments already delivered according to record are skipped.
+func (c *Connector) inboundAttachments(ctx context.Context, inbound transport.InboundTransport, transmission platform.Transmission, key string, record state.Record) error {
+	for _, messageId := range transmission.MessageIds {
+		c.logger.Debug("processing attachments for message", "messageId", messageId)
+		listCtx, cancel := context.WithTimeout(ctx, c.transferTimeout)
+		defer cancel()
+		attachments, err := c.platformClient.ListMessageAttachments(listCtx, messageId, inbound.AuthName())
+		if err != nil {
+			countFailure(ctx, metrics.PhaseList, inbound.ConfigId(), inbound.AuthName())
+			return fmt.Errorf("failed to list message attachments for %s: %w", messageId, err)
+		}
+
+		for _, attachment := range attachments {
+			c.logger.Debug("found attachment to handle", "attachmentUrl", attachment.Url)
+			if !inbound.HandleAttachment(attachment.Url) {
+				return nil
+			}
+			if slices.Contains(record.Attachments, attachment.Url) {
+				c.logger.Debug("attachment already delivered", "attachmentUrl", attachment.Url)
+				continue
+			}
+
+			if err := c.inboundAttachment(ctx, inbound, attachment.Url); err != nil {
+				return fmt.Errorf("failed to deliver attachment for %s: %w", messageId, err)
+			}
+
+			record, err = c.store.Update(key, func(r *state.Record) {
+				r.Attachments = append(r.Attachments, attachment.Url)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to journal attachment: %w", err)
+			}
+		}
+	}
+	return nil
+}
+
+// inboundAttachment streams the attachment at attachmentUrl into a staging file and delivers it.
+func (c *Connector) inboundAttachment(ctx context.Context, inbound transport.InboundTransport, attachmentUrl string) (err error) {
+	ctx, cancel := context.WithTimeout(ctx, c.transferTimeout)
+	defer cancel()
+	ctx, span := tracing.Start(ctx, "attachment", trace.WithAttributes(attribute.String("edi.attachment_url", attachmentUrl)))
+	defer func() { tracing.End(span, err) }()
+
+	var filename string
+	downloadCtx, downloadSpan := tracing.Start(ctx, "DownloadAttachment")
+	staged, err := c.receive(inbound, true, func(w io.Writer) (int64, error) {
+		var n int64
+		var err error
+		filename, n, err = c.downloadAttachment(downloadCtx, attachmentUrl, w)
+		return n, err
+	})
+	tracing.End(downloadSpan, err)
+	span.SetAttributes(tracing.Filename.String(filename))
+	if err != nil {
+		countFailure(ctx, metrics.PhaseDownload, inbound.ConfigId(), inbound.AuthName())
+		return err
+	}
+	defer func() {
+		if err := staged.remove(); err != nil {
+			c.logger.Error("failed to remove staging file", "path", staged.path, "error", err)
+		}
+	}()
+
+	processCtx, processSpan := tracing.Start(ctx, "ProcessAttachment")
+	err = inbound.ProcessAttachment(processCtx, staged.object(generateId(), map[string]string{
+		"filename": filename,
+	}))
+	tracing.End(processSpan, err)
+	if err != nil {
+		countFailure(ctx, metrics.PhaseProcess, inbound.ConfigId(), inbound.AuthName())
+		return fmt.Errorf("error processing attachment: %w", err)
+	}
+	countTransfer(ctx, metrics.Inbound, metrics.Attachment, inbound.ConfigId(), inbound.AuthName(), staged.size)
+	return nil
+}
+
+func generateId() string {
+	bytes := make([]byte, 16)
+	if _, err := rand.Read(bytes); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(bytes)
+}
+
+// downloadAttachment streams the attachment at attachmentUrl into w and returns its filename.
+func (c *Connector) downloadAttachment(ctx context.Context, attachmentUrl string, w io.Writer) (string, int64, error) {
+	if attachmentUrl == "" {
+		return "", 0, fmt.Errorf("attachment url couldn't be empty")
+	}
+	req, err := http.NewRequestWithContext(ctx, "GET", attachmentUrl, nil)
+	if err != nil {
+		return "", 0, fmt.Errorf("failed to create attachment request for %q: %w", attachmentUrl, err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("error while loading attachment with url %q: %w", attachmentUrl, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("error bad response for attachment %q: %w", attachmentUrl, err)
+	}
+
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Disposition"))
+	if err != nil {
+		return "", 0, fmt.Errorf("invalid content-disposition on attachment %q: %w", attachmentUrl, err)
+	}
+
+	filename, ok := params["filename"]
+	if !ok {
+		url, err := url.Parse(attachmentUrl)
+		if err != nil {
+			return "", 0, fmt.Errorf("failed to parse attachment url: %w", err)
+		}
+		slashIndex := strings.LastIndex(url.Path, "/")
+		if slashIndex != -1 {
+			filename = url.Path[slashIndex+1:]
+		}
+	}
+
+	n, err := io.Copy(w, resp.Body)
+	if err != nil {
+		return "", n, fmt.Errorf("error while writing response data for attachment %q: %w", attachmentUrl, err)
+	}
+	return filename, n, nil
+}
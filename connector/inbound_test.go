@@ -0,0 +1,127 @@
+package connector_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/connector"
+)
+
+func TestRejectedTransmissionListedAgain(t *testing.T) {
+	t.Setenv("EDI_CONNECTOR", "user:password")
+	var mu sync.Mutex
+	var downloads int
+	var confirms []bool
+	var platform *httptest.Server
+	platform = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		switch {
+		case r.URL.Path == "/v2/transmissions":
+			fmt.Fprintf(w, `{"transmissions": [{"id": "t1", "url": "%s/download/t1", "hash": {"method": "sha256", "sum": "00"}}]}`, platform.URL)
+		case r.URL.Path == "/download/t1":
+			downloads++
+			fmt.Fprint(w, "content")
+		case strings.HasSuffix(r.URL.Path, "/confirm"):
+			var confirm struct {
+				Error bool `json:"error"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&confirm); err != nil {
+				t.Errorf("Failed to decode confirmation: %v", err)
+			}
+			confirms = append(confirms, confirm.Error)
+			// the first confirmation is lost
+			if len(confirms) == 1 {
+				http.Error(w, "unavailable", http.StatusBadRequest)
+			}
+		}
+	}))
+	defer platform.Close()
+
+	cfg := testConfig(t, t.TempDir(), t.TempDir())
+	cfg.Url = platform.URL
+	cfg.Outbounds = nil
+	cfg.Inbounds = []config.ProcessConfig{{
+		Id:   "in",
+		Type: "FILE",
+		Settings: map[string]any{
+			"path": t.TempDir(),
+		},
+	}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c, err := connector.New(logger, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create connector: %v", err)
+	}
+
+	// hash verification fails, the negative confirmation is rejected by the platform
+	if _, err := c.RunOnce(context.Background()); err == nil {
+		t.Errorf("Expected failed confirmation to be reported")
+	}
+	// the pending negative confirmation is sent again
+	if _, err := c.RunOnce(context.Background()); err != nil {
+		t.Errorf("Failed to resend negative confirmation: %v", err)
+	}
+	// the transmission is already confirmed as failed
+	if _, err := c.RunOnce(context.Background()); err != nil {
+		t.Errorf("Failed to skip confirmed transmission: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if downloads != 3 {
+		t.Errorf("Expected transmission to be downloaded within the first run only, got %d downloads", downloads)
+	}
+	if len(confirms) != 2 {
+		t.Fatalf("Expected 2 confirmations, got: %v", confirms)
+	}
+	for i, isError := range confirms {
+		if !isError {
+			t.Errorf("Expected confirmation %d to be negative", i+1)
+		}
+	}
+}
+
+func TestFailedDownloadFailsRun(t *testing.T) {
+	t.Setenv("EDI_CONNECTOR", "user:password")
+	var platform *httptest.Server
+	platform = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v2/transmissions":
+			fmt.Fprintf(w, `{"transmissions": [{"id": "t1", "url": "%s/download/t1"}]}`, platform.URL)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer platform.Close()
+
+	cfg := testConfig(t, t.TempDir(), t.TempDir())
+	cfg.Url = platform.URL
+	cfg.Outbounds = nil
+	cfg.Inbounds = []config.ProcessConfig{{
+		Id:   "in",
+		Type: "FILE",
+		Settings: map[string]any{
+			"path": t.TempDir(),
+		},
+	}}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c, err := connector.New(logger, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create connector: %v", err)
+	}
+
+	_, err = c.RunOnce(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "t1") {
+		t.Errorf("Expected failed download of t1 to fail the run, got: %v", err)
+	}
+}
@@ -0,0 +1,83 @@
+package connector
+
+import (
+	"context"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/state"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+)
+
+// journalRetention is the time completed transfers are kept in the journal.
+const journalRetention = 24 * time.Hour
+
+// resume reconciles transfers interrupted by a previous run. Uploaded objects
+// are finalized and delivered or rejected transmissions are confirmed, so
+// neither is transferred a second time.
+func (c *Connector) resume(ctx context.Context) {
+	if c.dryRun {
+		c.logger.Info("dry run, transfers of a previous run aren't resumed")
+		return
+	}
+	for key, record := range c.store.Pending() {
+		switch record.Phase {
+		case state.PhaseUploaded:
+			c.resumeFinalize(ctx, key, record)
+		case state.PhaseDelivered:
+			c.logger.Info("resuming confirmation of delivered transmission", "configId", record.ConfigId, "transmissionId", record.ObjectId)
+			if err := c.confirmTransmission(ctx, key, record); err != nil {
+				c.logger.Error("failed to resume confirmation", "configId", record.ConfigId, "transmissionId", record.ObjectId, "error", err)
+			}
+		case state.PhaseRejected:
+			c.logger.Info("resuming confirmation of rejected transmission", "configId", record.ConfigId, "transmissionId", record.ObjectId)
+			if err := c.confirmRejection(ctx, key, record); err != nil {
+				c.logger.Error("failed to resume confirmation", "configId", record.ConfigId, "transmissionId", record.ObjectId, "error", err)
+			}
+		case state.PhaseRead:
+			// the upload may not have reached the platform, the object is
+			// read again with the next run of its transport
+			if err := c.store.Delete(key); err != nil {
+				c.logger.Error("failed to remove journal entry", "configId", record.ConfigId, "id", record.ObjectId, "error", err)
+			}
+		}
+	}
+	c.pruneJournal()
+}
+
+// resumeFinalize finalizes an object uploaded but not finalized by a previous run.
+func (c *Connector) resumeFinalize(ctx context.Context, key string, record state.Record) {
+	var outbound transport.OutboundTransport
+	for _, o := range c.outbounds() {
+		if o.ConfigId() == record.ConfigId {
+			outbound = o
+			break
+		}
+	}
+	if outbound == nil {
+		c.logger.Warn("no outbound transport configured for uploaded object", "configId", record.ConfigId, "id", record.ObjectId)
+		return
+	}
+
+	c.logger.Info("resuming finalization of uploaded object", "configId", record.ConfigId, "id", record.ObjectId)
+	if finalizer, ok := outbound.(transport.Finalizer); ok {
+		ctx, cancel := context.WithTimeout(ctx, c.transferTimeout)
+		defer cancel()
+		obj := transport.Object{Id: record.ObjectId, Metadata: record.Metadata}
+		if err := finalizer.Finalize(ctx, obj, nil); err != nil {
+			c.logger.Error("failed to resume finalization", "configId", record.ConfigId, "id", record.ObjectId, "error", err)
+			return
+		}
+	}
+	if _, err := c.store.Update(key, func(r *state.Record) { r.Phase = state.PhaseFinalized }); err != nil {
+		c.logger.Error("failed to journal finalization", "configId", record.ConfigId, "id", record.ObjectId, "error", err)
+	}
+}
+
+// pruneJournal removes completed transfers older than the journal retention.
+// Uploads are kept at least for the duplicate window.
+func (c *Connector) pruneJournal() {
+	retention := max(journalRetention, c.duplicateWindow)
+	if err := c.store.Prune(time.Now().Add(-retention)); err != nil {
+		c.logger.Error("failed to prune journal", "error", err)
+	}
+}
@@ -0,0 +1,135 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/metrics"
+)
+
+// RunSummary is the result of a single run of a transport. In a dry run the
+// transfers which would have been executed are counted.
+type RunSummary struct {
+	Kind       string `json:"kind"`
+	ConfigId   string `json:"configId"`
+	Uploaded   int64  `json:"uploaded"`
+	Downloaded int64  `json:"downloaded"`
+	Failed     int64  `json:"failed"`
+	Duration   string `json:"duration"`
+	Error      string `json:"error,omitempty"`
+}
+
+// tally counts the transfers of a run, it is passed to the run within the context.
+type tally struct {
+	uploaded   atomic.Int64
+	downloaded atomic.Int64
+	failed     atomic.Int64
+}
+
+type tallyKey struct{}
+
+// countTransfer records a transfer in the metrics and the tally of the run in ctx.
+func countTransfer(ctx context.Context, direction, kind, configId, authName string, size int64) {
+	metrics.Transferred(direction, kind, configId, authName, size)
+	tallyTransfer(ctx, direction)
+}
+
+// tallyTransfer records a transfer in the tally of the run in ctx.
+func tallyTransfer(ctx context.Context, direction string) {
+	if t, ok := ctx.Value(tallyKey{}).(*tally); ok {
+		if direction == metrics.Outbound {
+			t.uploaded.Add(1)
+		} else {
+			t.downloaded.Add(1)
+		}
+	}
+}
+
+// countFailure records a failure in the metrics and the tally of the run in ctx.
+func countFailure(ctx context.Context, phase metrics.Phase, configId, authName string) {
+	metrics.Failed(phase, configId, authName)
+	if t, ok := ctx.Value(tallyKey{}).(*tally); ok {
+		t.failed.Add(1)
+	}
+}
+
+// RunOnce runs all transports, or only those of configIds, a single time
+// and returns once all runs completed. Transfers left over by a previous run
+// are resumed first. Unlike Run neither the admin api nor the metrics are
+// served. An error is returned if a transport failed or a transfer of it
+// failed, the summaries report the transfers of each transport.
+func (c *Connector) RunOnce(ctx context.Context, configIds ...string) ([]RunSummary, error) {
+	defer c.listener.Close()
+	if c.metricsListener != nil {
+		defer c.metricsListener.Close()
+	}
+	defer func() { c.closeTransports(c.snapshot()) }()
+	defer c.flushTraces(ctx)
+
+	var workers []*worker
+	for _, w := range c.snapshot() {
+		if len(configIds) == 0 || slices.Contains(configIds, w.configId) {
+			workers = append(workers, w)
+		}
+	}
+	for _, configId := range configIds {
+		if !slices.ContainsFunc(workers, func(w *worker) bool { return w.configId == configId }) {
+			return nil, fmt.Errorf("no transport configured with config id %s", configId)
+		}
+	}
+
+	c.resume(ctx)
+
+	summaries := make([]RunSummary, len(workers))
+	var wg sync.WaitGroup
+	for i, w := range workers {
+		wg.Go(func() {
+			summaries[i] = c.runOnce(ctx, w)
+		})
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, s := range summaries {
+		switch {
+		case s.Error != "":
+			errs = append(errs, fmt.Errorf("%s transport %s: %s", s.Kind, s.ConfigId, s.Error))
+		case s.Failed > 0:
+			errs = append(errs, fmt.Errorf("%s transport %s: %d transfers failed", s.Kind, s.ConfigId, s.Failed))
+		}
+	}
+	return summaries, errors.Join(errs...)
+}
+
+// runOnce runs w within a slot of the connector and summarizes the run.
+func (c *Connector) runOnce(ctx context.Context, w *worker) RunSummary {
+	summary := RunSummary{Kind: w.kind, ConfigId: w.configId}
+	select {
+	case c.slots <- struct{}{}:
+	case <-ctx.Done():
+		summary.Error = ctx.Err().Error()
+		return summary
+	}
+	defer func() { <-c.slots }()
+
+	t := &tally{}
+	start := time.Now()
+	err := w.run(context.WithValue(ctx, tallyKey{}, t))
+	summary.Duration = time.Since(start).String()
+	summary.Uploaded = t.uploaded.Load()
+	summary.Downloaded = t.downloaded.Load()
+	summary.Failed = t.failed.Load()
+	if err != nil {
+		c.logger.Error("error processing "+w.kind+" transport", "configId", w.configId, "error", err)
+		summary.Error = err.Error()
+		return summary
+	}
+	metrics.RunSucceeded(w.kind, w.configId, w.authName)
+	c.logger.Info("processed "+w.kind+" transport", "configId", w.configId, "uploaded", summary.Uploaded, "downloaded", summary.Downloaded, "failed", summary.Failed, "duration", summary.Duration)
+	return summary
+}
@@ -0,0 +1,179 @@
+package connector_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/connector"
+)
+
+func TestRunOnce(t *testing.T) {
+	t.Setenv("EDI_CONNECTOR", "user:password")
+	platform := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) == "bad" {
+			http.Error(w, "invalid message", http.StatusBadRequest)
+		}
+	}))
+	defer platform.Close()
+
+	messageDir := t.TempDir()
+	errorDir := t.TempDir()
+	cfg := testConfig(t, messageDir, errorDir)
+	cfg.Url = platform.URL
+	cfg.Outbounds[0].Settings["message"].(map[string]any)["waitTime"] = "0s"
+	for i, name := range []string{"good", "bad"} {
+		path := filepath.Join(messageDir, name+".txt")
+		if err := os.WriteFile(path, []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to write message: %v", err)
+		}
+		modified := time.Now().Add(time.Duration(i-2) * time.Minute)
+		if err := os.Chtimes(path, modified, modified); err != nil {
+			t.Fatalf("Failed to change modification time: %v", err)
+		}
+	}
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c, err := connector.New(logger, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create connector: %v", err)
+	}
+	summaries, err := c.RunOnce(context.Background())
+	if err == nil {
+		t.Errorf("Expected failed upload to be reported")
+	}
+	if len(summaries) != 1 {
+		t.Fatalf("Expected 1 summary, got: %d", len(summaries))
+	}
+	summary := summaries[0]
+	if summary.ConfigId != "out" || summary.Uploaded != 1 || summary.Failed != 1 || summary.Error == "" {
+		t.Errorf("Expected 1 uploaded and 1 failed message of out, got: %+v", summary)
+	}
+	if _, err := os.Stat(filepath.Join(errorDir, "bad.txt")); err != nil {
+		t.Errorf("Expected failed message within error folder: %v", err)
+	}
+}
+
+func TestRunOnceUnknownConfigId(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	c, err := connector.New(logger, testConfig(t, t.TempDir(), t.TempDir()))
+	if err != nil {
+		t.Fatalf("Failed to create connector: %v", err)
+	}
+	if _, err := c.RunOnce(context.Background(), "missing"); err == nil {
+		t.Errorf("Expected unknown config id to fail")
+	}
+}
+
+func TestRunOnceDryRun(t *testing.T) {
+	t.Setenv("EDI_CONNECTOR", "user:password")
+	platform := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			t.Errorf("Expected no %s %s within dry run", r.Method, r.URL.Path)
+			return
+		}
+		fmt.Fprint(w, `{"transmissions": [{"id": "t1", "url": "http://invalid/t1", "metadata": {"filename": "order.xml"}}]}`)
+	}))
+	defer platform.Close()
+
+	messageDir := t.TempDir()
+	inboundDir := t.TempDir()
+	cfg := testConfig(t, messageDir, t.TempDir())
+	cfg.Url = platform.URL
+	cfg.DryRun = true
+	cfg.Outbounds[0].Settings["message"].(map[string]any)["waitTime"] = "0s"
+	cfg.Inbounds = []config.ProcessConfig{{
+		Id:   "in",
+		Type: "FILE",
+		Settings: map[string]any{
+			"path": inboundDir,
+		},
+	}}
+	message := filepath.Join(messageDir, "message.txt")
+	if err := os.WriteFile(message, []byte("message"), 0644); err != nil {
+		t.Fatalf("Failed to write message: %v", err)
+	}
+	modified := time.Now().Add(-time.Minute)
+	if err := os.Chtimes(message, modified, modified); err != nil {
+		t.Fatalf("Failed to change modification time: %v", err)
+	}
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, nil))
+	c, err := connector.New(logger, cfg)
+	if err != nil {
+		t.Fatalf("Failed to create connector: %v", err)
+	}
+	summaries, err := c.RunOnce(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to run once: %v", err)
+	}
+	for _, summary := range summaries {
+		if summary.Uploaded+summary.Downloaded != 1 || summary.Failed != 0 {
+			t.Errorf("Expected a single planned transfer, got: %+v", summary)
+		}
+	}
+	if _, err := os.Stat(message); err != nil {
+		t.Errorf("Expected message to be kept: %v", err)
+	}
+	if entries, _ := os.ReadDir(inboundDir); len(entries) != 0 {
+		t.Errorf("Expected no delivered files, got: %d", len(entries))
+	}
+	if target := filepath.Join(inboundDir, "order.xml"); !strings.Contains(logs.String(), "target="+target) {
+		t.Errorf("Expected target %s to be logged, got: %s", target, logs.String())
+	}
+}
+
+func TestRunOnceDuplicateWindow(t *testing.T) {
+	t.Setenv("EDI_CONNECTOR", "user:password")
+	for _, window := range []string{"", "1h"} {
+		var uploads atomic.Int32
+		platform := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			uploads.Add(1)
+		}))
+		defer platform.Close()
+
+		messageDir := t.TempDir()
+		errorDir := t.TempDir()
+		cfg := testConfig(t, messageDir, errorDir)
+		cfg.Url = platform.URL
+		cfg.DuplicateWindow = window
+		cfg.MaxConcurrency = 1
+		cfg.Outbounds[0].Settings["message"].(map[string]any)["waitTime"] = "0s"
+		for _, name := range []string{"first.txt", "second.txt"} {
+			if err := os.WriteFile(filepath.Join(messageDir, name), []byte("status"), 0644); err != nil {
+				t.Fatalf("Failed to write message: %v", err)
+			}
+		}
+
+		logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+		c, err := connector.New(logger, cfg)
+		if err != nil {
+			t.Fatalf("Failed to create connector: %v", err)
+		}
+		if _, err := c.RunOnce(context.Background()); err != nil {
+			t.Fatalf("Failed to run with duplicate window %q: %v", window, err)
+		}
+		entries, _ := os.ReadDir(errorDir)
+		// identical messages are only skipped if the duplicate window is configured
+		expectedUploads, expectedDuplicates := int32(2), 0
+		if window != "" {
+			expectedUploads, expectedDuplicates = 1, 1
+		}
+		if uploads.Load() != expectedUploads || len(entries) != expectedDuplicates {
+			t.Errorf("Expected %d uploads and %d duplicates with duplicate window %q, got %d uploads and %d duplicates", expectedUploads, expectedDuplicates, window, uploads.Load(), len(entries))
+		}
+	}
+}
@@ -0,0 +1,298 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"iter"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/metrics"
+	"github.com/myopenfactory/edi-connector/v2/state"
+	"github.com/myopenfactory/edi-connector/v2/tracing"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func (c *Connector) outboundMessages(ctx context.Context, outbound transport.OutboundTransport, concurrency int) error {
+	listCtx, listSpan := tracing.Start(ctx, "list messages", trace.WithAttributes(transportAttributes(outbound)...))
+	defer listSpan.End()
+
+	return forEachSeq(ctx, c.listMessages(listCtx, outbound), concurrency, func(ctx context.Context, msg transport.Object) (err error) {
+		ctx, span := tracing.StartRoot(ctx, "outbound message", listSpan.SpanContext(), objectAttributes(outbound, msg)...)
+		defer func() { tracing.End(span, err) }()
+
+		hash, err := readObject(ctx, msg)
+		if err != nil {
+			return err
+		}
+		if c.dryRun {
+			if duplicate, ok := c.findDuplicate(outbound, msg, hash); ok {
+				c.logger.Warn("skipping upload of duplicate message", "configId", outbound.ConfigId(), "id", msg.Id, "uploadedId", duplicate.ObjectId, "uploaded", duplicate.Updated)
+				return nil
+			}
+			c.dryRunUpload(ctx, outbound, metrics.Message, msg, hash)
+			return nil
+		}
+		err = c.upload(ctx, outbound, metrics.Message, msg, hash, c.duplicateWindow > 0, func(ctx context.Context, content io.Reader, size int64) error {
+			ctx, span := tracing.Start(ctx, "AddTransmission")
+			err := c.platformClient.AddTransmissionStream(ctx, outbound.ConfigId(), outbound.AuthName(), content, size)
+			tracing.End(span, err)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to process message %s: %w", msg.Id, err)
+		}
+		return nil
+	})
+}
+
+func (c *Connector) outboundAttachments(ctx context.Context, outbound transport.OutboundTransport, concurrency int) error {
+	listCtx, listSpan := tracing.Start(ctx, "list attachments", trace.WithAttributes(transportAttributes(outbound)...))
+	defer listSpan.End()
+
+	attachments := func(yield func(transport.Object, error) bool) {
+		for attachment, err := range c.listAttachments(listCtx, outbound) {
+			if err != nil {
+				c.logger.Error("error while reading attachment", "error", err)
+				return
+			}
+			if !yield(attachment, nil) {
+				return
+			}
+		}
+	}
+
+	return forEachSeq(ctx, attachments, concurrency, func(ctx context.Context, attachment transport.Object) (err error) {
+		ctx, span := tracing.StartRoot(ctx, "outbound attachment", listSpan.SpanContext(), objectAttributes(outbound, attachment)...)
+		defer func() { tracing.End(span, err) }()
+
+		hash, err := readObject(ctx, attachment)
+		if err != nil {
+			return err
+		}
+		if c.dryRun {
+			c.dryRunUpload(ctx, outbound, metrics.Attachment, attachment, hash)
+			return nil
+		}
+		err = c.upload(ctx, outbound, metrics.Attachment, attachment, hash, false, func(ctx context.Context, content io.Reader, size int64) error {
+			ctx, span := tracing.Start(ctx, "AddAttachment")
+			err := c.platformClient.AddAttachmentStream(ctx, content, size, attachment.Id, outbound.AuthName())
+			tracing.End(span, err)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to process attachment %s: %w", attachment.Id, err)
+		}
+		return nil
+	})
+}
+
+// listMessages lists the messages of outbound, lazily if it implements transport.Lister.
+func (c *Connector) listMessages(ctx context.Context, outbound transport.OutboundTransport) iter.Seq2[transport.Object, error] {
+	if lister, ok := outbound.(transport.Lister); ok {
+		return countListFailures(ctx, outbound, lister.Messages(ctx))
+	}
+	return countListFailures(ctx, outbound, c.list(ctx, outbound.ListMessages))
+}
+
+// listAttachments lists the attachments of outbound, lazily if it implements transport.Lister.
+func (c *Connector) listAttachments(ctx context.Context, outbound transport.OutboundTransport) iter.Seq2[transport.Object, error] {
+	if lister, ok := outbound.(transport.Lister); ok {
+		return countListFailures(ctx, outbound, lister.Attachments(ctx))
+	}
+	return countListFailures(ctx, outbound, c.list(ctx, outbound.ListAttachments))
+}
+
+// list yields the objects returned by the list function within the transfer timeout.
+func (c *Connector) list(ctx context.Context, list func(context.Context) ([]transport.Object, error)) iter.Seq2[transport.Object, error] {
+	return func(yield func(transport.Object, error) bool) {
+		listCtx, cancel := context.WithTimeout(ctx, c.transferTimeout)
+		defer cancel()
+		objects, err := list(listCtx)
+		if err != nil {
+			yield(transport.Object{}, fmt.Errorf("failed to list: %w", err))
+			return
+		}
+		for _, obj := range objects {
+			if !yield(obj, nil) {
+				return
+			}
+		}
+	}
+}
+
+// countListFailures counts the errors yielded by seq as failed listings of
+// outbound and marks the listing span in ctx as failed.
+func countListFailures(ctx context.Context, outbound transport.OutboundTransport, seq iter.Seq2[transport.Object, error]) iter.Seq2[transport.Object, error] {
+	return func(yield func(transport.Object, error) bool) {
+		for obj, err := range seq {
+			if err != nil {
+				countFailure(ctx, metrics.PhaseList, outbound.ConfigId(), outbound.AuthName())
+				tracing.Fail(ctx, err)
+			}
+			if !yield(obj, err) {
+				return
+			}
+		}
+	}
+}
+
+// upload streams the content of obj with the upload function and finalizes
+// it afterwards. Each step is journaled, an object already uploaded by a
+// previous run is only finalized and not uploaded again. If dedupe is set an
+// object with the content of an upload within the duplicate window is
+// finalized as duplicate instead. The kind of obj is used for metrics.
+func (c *Connector) upload(ctx context.Context, outbound transport.OutboundTransport, kind string, obj transport.Object, hash string, dedupe bool, upload func(context.Context, io.Reader, int64) error) error {
+	key := state.OutboundKey(outbound.ConfigId(), obj.Id, hash)
+	finalizer, isFinalizer := outbound.(transport.Finalizer)
+
+	ctx, cancel := context.WithTimeout(ctx, c.transferTimeout)
+	defer cancel()
+
+	record, _ := c.store.Get(key)
+	if record.Phase == state.PhaseUploaded {
+		c.logger.Info("object already uploaded, resuming finalization", "configId", outbound.ConfigId(), "id", obj.Id)
+	} else {
+		journal := func(r *state.Record) {
+			r.Direction = state.DirectionOutbound
+			r.ConfigId = outbound.ConfigId()
+			r.AuthName = outbound.AuthName()
+			r.ObjectId = obj.Id
+			r.Hash = hash
+			r.Metadata = obj.Metadata
+			r.Phase = state.PhaseRead
+		}
+		var err error
+		if dedupe {
+			var duplicate state.Record
+			var isDuplicate bool
+			duplicate, isDuplicate, err = c.store.UpdateUnique(key, hash, c.isDuplicate(outbound, key), journal)
+			if err == nil && isDuplicate {
+				return c.skipDuplicate(ctx, outbound, obj, duplicate)
+			}
+		} else {
+			_, err = c.store.Update(key, journal)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to journal object: %w", err)
+		}
+
+		size, err := c.uploadContent(ctx, obj, upload)
+		if err != nil && ctx.Err() != nil {
+			// the upload was interrupted, e.g. by a shutdown, the object
+			// itself didn't fail and is read again with the next run
+			if err := c.store.Delete(key); err != nil {
+				c.logger.Error("failed to remove journal entry", "configId", outbound.ConfigId(), "id", obj.Id, "error", err)
+			}
+			return fmt.Errorf("upload interrupted: %w", err)
+		}
+		if err != nil {
+			countFailure(ctx, metrics.PhaseUpload, outbound.ConfigId(), outbound.AuthName())
+			if isFinalizer {
+				if finalizerErr := finalize(ctx, finalizer, obj, err); finalizerErr != nil {
+					return fmt.Errorf("could not finalize after failed upload: %w", finalizerErr)
+				}
+			}
+			if err := c.store.Delete(key); err != nil {
+				c.logger.Error("failed to remove journal entry", "configId", outbound.ConfigId(), "id", obj.Id, "error", err)
+			}
+			return fmt.Errorf("failed to upload: %w", err)
+		}
+		countTransfer(ctx, metrics.Outbound, kind, outbound.ConfigId(), outbound.AuthName(), size)
+
+		if _, err := c.store.Update(key, func(r *state.Record) { r.Phase = state.PhaseUploaded }); err != nil {
+			c.logger.Error("failed to journal upload", "configId", outbound.ConfigId(), "id", obj.Id, "error", err)
+		}
+	}
+
+	if isFinalizer {
+		if err := finalize(ctx, finalizer, obj, nil); err != nil {
+			countFailure(ctx, metrics.PhaseFinalize, outbound.ConfigId(), outbound.AuthName())
+			return fmt.Errorf("could not finalize: %w", err)
+		}
+	}
+	if _, err := c.store.Update(key, func(r *state.Record) { r.Phase = state.PhaseFinalized }); err != nil {
+		c.logger.Error("failed to journal finalization", "configId", outbound.ConfigId(), "id", obj.Id, "error", err)
+	}
+	return nil
+}
+
+// finalize finalizes obj with the result of its upload.
+func finalize(ctx context.Context, finalizer transport.Finalizer, obj transport.Object, result error) error {
+	ctx, span := tracing.Start(ctx, "Finalize")
+	err := finalizer.Finalize(ctx, obj, result)
+	tracing.End(span, err)
+	return err
+}
+
+// readObject reads the content of obj to compute its hash.
+func readObject(ctx context.Context, obj transport.Object) (string, error) {
+	_, span := tracing.Start(ctx, "read")
+	hash, err := objectHash(obj)
+	tracing.End(span, err)
+	return hash, err
+}
+
+// transportAttributes returns the span attributes identifying a transport.
+func transportAttributes(t transport.ConfigInfo) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		tracing.ConfigId.String(t.ConfigId()),
+		tracing.AuthName.String(t.AuthName()),
+	}
+}
+
+// objectAttributes returns the span attributes identifying obj of outbound.
+func objectAttributes(outbound transport.OutboundTransport, obj transport.Object) []attribute.KeyValue {
+	return append(transportAttributes(outbound), tracing.Filename.String(obj.Id))
+}
+
+// uploadContent opens the content of obj and passes it to the upload function.
+// It returns the size of the uploaded content.
+func (c *Connector) uploadContent(ctx context.Context, obj transport.Object, upload func(context.Context, io.Reader, int64) error) (int64, error) {
+	content, size, err := obj.Reader()
+	if err != nil {
+		return 0, fmt.Errorf("failed to read content: %w", err)
+	}
+	defer content.Close()
+	return size, upload(ctx, content, size)
+}
+
+// skipDuplicate finalizes obj as duplicate of the record of an uploaded object.
+func (c *Connector) skipDuplicate(ctx context.Context, outbound transport.OutboundTransport, obj transport.Object, duplicate state.Record) error {
+	c.logger.Warn("skipping upload of duplicate message", "configId", outbound.ConfigId(), "id", obj.Id, "uploadedId", duplicate.ObjectId, "uploaded", duplicate.Updated)
+	if finalizer, ok := outbound.(transport.Finalizer); ok {
+		if err := finalize(ctx, finalizer, obj, transport.ErrDuplicate); err != nil {
+			countFailure(ctx, metrics.PhaseFinalize, outbound.ConfigId(), outbound.AuthName())
+			return fmt.Errorf("could not finalize duplicate message %s: %w", obj.Id, err)
+		}
+	}
+	return nil
+}
+
+// findDuplicate returns the record of a message with the same content hash as
+// msg uploaded for the transport within the duplicate window.
+func (c *Connector) findDuplicate(outbound transport.OutboundTransport, msg transport.Object, hash string) (state.Record, bool) {
+	if c.duplicateWindow <= 0 {
+		return state.Record{}, false
+	}
+	key := state.OutboundKey(outbound.ConfigId(), msg.Id, hash)
+	return c.store.FindHash(hash, c.isDuplicate(outbound, key))
+}
+
+// isDuplicate returns a filter for records with the content of the object
+// journaled at key. These are records of outbound uploaded within the
+// duplicate window or still being uploaded by another object.
+func (c *Connector) isDuplicate(outbound transport.OutboundTransport, key string) func(string, state.Record) bool {
+	cutoff := time.Now().Add(-c.duplicateWindow)
+	return func(k string, r state.Record) bool {
+		if r.Direction != state.DirectionOutbound || r.ConfigId != outbound.ConfigId() {
+			return false
+		}
+		if r.Phase == state.PhaseRead {
+			return k != key
+		}
+		return (r.Phase == state.PhaseUploaded || r.Phase == state.PhaseFinalized) && r.Updated.After(cutoff)
+	}
+}
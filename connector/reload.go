@@ -0,0 +1,251 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"maps"
+	"os"
+	"reflect"
+	"slices"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/log"
+	"github.com/myopenfactory/edi-connector/v2/metrics"
+)
+
+// configPollInterval is the interval the config file is checked for changes.
+const configPollInterval = 5 * time.Second
+
+// Reload applies the processes of cfg to the running connector. Transports
+// with an unchanged process config keep running, removed and changed
+// transports are stopped once their run in progress completed. If a
+// transport of cfg can't be created the current config is kept. Besides the
+// processes only the runWaitTime and the log level are applied, other
+// changed settings require a restart.
+func (c *Connector) Reload(cfg config.Config) error {
+	c.reloadMu.Lock()
+	defer c.reloadMu.Unlock()
+
+	interval, err := time.ParseDuration(cfg.RunWaitTime)
+	if err != nil {
+		return fmt.Errorf("failed to parse runWaitTime duration: %w", err)
+	}
+	if err := checkProcessIds(cfg); err != nil {
+		return err
+	}
+
+	c.mu.RLock()
+	old := c.config
+	current := make(map[string]*worker, len(c.workers))
+	for _, w := range c.workers {
+		current[processKey(w.kind, w.configId)] = w
+	}
+	intervalChanged := interval != c.runWaitTime
+	c.mu.RUnlock()
+
+	var next, started, stale []*worker
+	for kind, pc := range processes(cfg) {
+		key := processKey(kind, pc.Id)
+		w, exists := current[key]
+		if exists && reflect.DeepEqual(w.process, pc) && (pc.RunWaitTime != "" || !intervalChanged) {
+			next = append(next, w)
+			delete(current, key)
+			continue
+		}
+
+		nw, err := c.newProcess(kind, pc, interval)
+		if err != nil {
+			c.closeTransports(started)
+			return err
+		}
+		if exists {
+			c.logger.Info("changed "+kind+" process", "configId", pc.Id, "changes", processChanges(w.process, pc))
+			nw.setPaused(w.isPaused())
+			stale = append(stale, w)
+			delete(current, key)
+		} else {
+			c.logger.Info("added "+kind+" process", "configId", pc.Id, "type", pc.Type)
+		}
+		next = append(next, nw)
+		started = append(started, nw)
+	}
+	for _, w := range current {
+		c.logger.Info("removed "+w.kind+" process", "configId", w.configId)
+		stale = append(stale, w)
+	}
+
+	for _, w := range stale {
+		w.halt()
+	}
+	c.closeTransports(stale)
+
+	c.mu.Lock()
+	c.workers = next
+	c.config = cfg
+	c.runWaitTime = interval
+	if c.runCtx != nil {
+		for _, w := range started {
+			c.start(c.runCtx, w)
+		}
+	}
+	c.mu.Unlock()
+
+	if cfg.Log.Level != old.Log.Level {
+		if level, err := log.ParseLevel(cfg.Log.Level); err == nil {
+			log.SetLevel(level)
+			c.logger.Info("changed log level", "level", level)
+		}
+	}
+	if intervalChanged {
+		c.logger.Info("changed runWaitTime", "runWaitTime", interval)
+	}
+	if settings := restartRequired(old, cfg); len(settings) > 0 {
+		c.logger.Warn("changed settings are applied after a restart", "settings", settings)
+	}
+	c.logger.Info("reloaded config", "started", len(started), "stopped", len(stale), "unchanged", len(next)-len(started))
+	return nil
+}
+
+// WatchConfig reloads the config returned by load whenever the file at path
+// changed or a signal is received on reload, until ctx is done. If the config
+// can't be loaded or applied, the current config is kept.
+func (c *Connector) WatchConfig(ctx context.Context, path string, load func() (config.Config, error), reload <-chan os.Signal) {
+	last := statFile(path)
+	ticker := time.NewTicker(configPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			current := statFile(path)
+			if current == last {
+				continue
+			}
+			last = current
+			c.logger.Info("config file changed, reloading", "path", path)
+		case sig := <-reload:
+			last = statFile(path)
+			c.logger.Info("reloading config on signal", "path", path, "signal", sig)
+		case <-ctx.Done():
+			return
+		}
+
+		cfg, err := load()
+		if err != nil {
+			c.logger.Error("failed to load config, keeping current config", "path", path, "error", err)
+			continue
+		}
+		if err := c.Reload(cfg); err != nil {
+			c.logger.Error("invalid config, keeping current config", "path", path, "error", err)
+		}
+	}
+}
+
+// fileState identifies a version of a file.
+type fileState struct {
+	modTime time.Time
+	size    int64
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{}
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size()}
+}
+
+// processes yields the kind and config of all processes of cfg.
+func processes(cfg config.Config) iter.Seq2[string, config.ProcessConfig] {
+	return func(yield func(string, config.ProcessConfig) bool) {
+		for _, pc := range cfg.Outbounds {
+			if !yield(metrics.Outbound, pc) {
+				return
+			}
+		}
+		for _, pc := range cfg.Inbounds {
+			if !yield(metrics.Inbound, pc) {
+				return
+			}
+		}
+	}
+}
+
+func processKey(kind, configId string) string {
+	return kind + "/" + configId
+}
+
+// checkProcessIds rejects processes of the same kind sharing an id.
+func checkProcessIds(cfg config.Config) error {
+	seen := make(map[string]bool)
+	for kind, pc := range processes(cfg) {
+		key := processKey(kind, pc.Id)
+		if seen[key] {
+			return fmt.Errorf("duplicate %s process id %s", kind, pc.Id)
+		}
+		seen[key] = true
+	}
+	return nil
+}
+
+// processChanges lists the settings changed between old and new.
+func processChanges(old, new config.ProcessConfig) []string {
+	var changes []string
+	if old.Type != new.Type {
+		changes = append(changes, "type")
+	}
+	if old.AuthName != new.AuthName {
+		changes = append(changes, "authName")
+	}
+	if old.RunWaitTime != new.RunWaitTime {
+		changes = append(changes, "runWaitTime")
+	}
+	if old.Concurrency != new.Concurrency {
+		changes = append(changes, "concurrency")
+	}
+	if !reflect.DeepEqual(old.Schedule, new.Schedule) {
+		changes = append(changes, "schedule")
+	}
+	keys := slices.Sorted(maps.Keys(old.Settings))
+	for _, key := range slices.Sorted(maps.Keys(new.Settings)) {
+		if _, ok := old.Settings[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range keys {
+		if !reflect.DeepEqual(old.Settings[key], new.Settings[key]) {
+			changes = append(changes, "settings."+key)
+		}
+	}
+	if len(changes) == 0 {
+		changes = append(changes, "runWaitTime")
+	}
+	return changes
+}
+
+// restartRequired lists the changed settings which aren't applied by Reload.
+func restartRequired(old, new config.Config) []string {
+	var settings []string
+	check := func(name string, changed bool) {
+		if changed {
+			settings = append(settings, name)
+		}
+	}
+	check("instancePort", old.InstancePort != new.InstancePort)
+	check("proxy", old.Proxy != new.Proxy)
+	check("url", old.Url != new.Url)
+	check("caFile", old.CAFile != new.CAFile)
+	check("dataDir", old.DataDir != new.DataDir)
+	check("maxDeliveryAttempts", old.MaxDeliveryAttempts != new.MaxDeliveryAttempts)
+	check("duplicateWindow", old.DuplicateWindow != new.DuplicateWindow)
+	check("maxConcurrency", old.MaxConcurrency != new.MaxConcurrency)
+	check("transferTimeout", old.TransferTimeout != new.TransferTimeout)
+	check("adminToken", old.AdminToken != new.AdminToken)
+	check("metricsAddress", old.MetricsAddress != new.MetricsAddress)
+	check("tracing", !reflect.DeepEqual(old.Tracing, new.Tracing))
+	check("credentials", old.Credentials != new.Credentials)
+	check("log.type", old.Log.Type != new.Log.Type)
+	check("log.folder", old.Log.Folder != new.Log.Folder)
+	return settings
+}
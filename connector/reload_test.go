@@ -0,0 +1,94 @@
+package connector_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+)
+
+func TestReload(t *testing.T) {
+	cfg := testConfig(t, t.TempDir(), t.TempDir())
+	c, call := runConnector(t, cfg)
+
+	transports := func() []string {
+		t.Helper()
+		status, body := call(http.MethodGet, "/api/transports", "secret", "")
+		if status != http.StatusOK {
+			t.Fatalf("Expected status %d, got: %d", http.StatusOK, status)
+		}
+		var statuses []struct {
+			Kind     string `json:"kind"`
+			ConfigId string `json:"configId"`
+			Paused   bool   `json:"paused"`
+		}
+		if err := json.Unmarshal([]byte(body), &statuses); err != nil {
+			t.Fatalf("Failed to decode transports: %v", err)
+		}
+		var ids []string
+		for _, s := range statuses {
+			ids = append(ids, s.Kind+"/"+s.ConfigId)
+		}
+		return ids
+	}
+
+	inbound := config.ProcessConfig{
+		Id:   "in",
+		Type: "FILE",
+		Settings: map[string]any{
+			"path": t.TempDir(),
+		},
+	}
+	added := cfg
+	added.Inbounds = []config.ProcessConfig{inbound}
+	if err := c.Reload(added); err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if ids := transports(); len(ids) != 2 || ids[0] != "outbound/out" || ids[1] != "inbound/in" {
+		t.Errorf("Expected outbound/out and inbound/in, got: %v", ids)
+	}
+
+	if status, _ := call(http.MethodPost, "/api/transports/outbound/out/pause", "secret", ""); status != http.StatusOK {
+		t.Fatalf("Expected status %d, got: %d", http.StatusOK, status)
+	}
+	changed := added
+	changed.Outbounds = []config.ProcessConfig{added.Outbounds[0]}
+	changed.Outbounds[0].Concurrency = 2
+	if err := c.Reload(changed); err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if status, body := call(http.MethodPost, "/api/transports/outbound/out/run", "secret", ""); status != http.StatusConflict {
+		t.Errorf("Expected replaced transport to stay paused, got: %d %s", status, body)
+	}
+
+	invalid := changed
+	invalid.Inbounds = []config.ProcessConfig{inbound, {
+		Id:   "broken",
+		Type: "FILE",
+		Settings: map[string]any{
+			"path": filepath.Join(t.TempDir(), "missing"),
+		},
+	}}
+	if err := c.Reload(invalid); err == nil {
+		t.Errorf("Expected reload of invalid config to fail")
+	}
+	duplicate := changed
+	duplicate.Inbounds = []config.ProcessConfig{inbound, inbound}
+	if err := c.Reload(duplicate); err == nil {
+		t.Errorf("Expected reload of duplicate process ids to fail")
+	}
+	if ids := transports(); len(ids) != 2 {
+		t.Errorf("Expected current transports to be kept, got: %v", ids)
+	}
+
+	removed := changed
+	removed.Outbounds = nil
+	if err := c.Reload(removed); err != nil {
+		t.Fatalf("Failed to reload config: %v", err)
+	}
+	if ids := transports(); len(ids) != 1 || ids[0] != "inbound/in" {
+		t.Errorf("Expected only inbound/in, got: %v", ids)
+	}
+}
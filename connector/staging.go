@@ -0,0 +1,82 @@
+package connector
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/myopenfactory/edi-connector/v2/transport"
+)
+
+// stagedFile is content received from the platform, kept in a temporary file
+// until it is delivered.
+type stagedFile struct {
+	path string
+	size int64
+	hash string
+}
+
+// receive stages the content written by fetch in a temporary file. The file is
+// placed in the staging folder of inbound if it provides one.
+func (c *Connector) receive(inbound transport.InboundTransport, attachment bool, fetch func(io.Writer) (int64, error)) (stagedFile, error) {
+	dir := ""
+	if stager, ok := inbound.(transport.Stager); ok {
+		dir = stager.StagingDir(attachment)
+	}
+	f, err := os.CreateTemp(dir, ".edi-connector-*.part")
+	if err != nil {
+		return stagedFile{}, fmt.Errorf("failed to create staging file: %w", err)
+	}
+
+	h := sha256.New()
+	size, err := fetch(io.MultiWriter(f, h))
+	if closeErr := f.Close(); err == nil && closeErr != nil {
+		err = fmt.Errorf("failed to close staging file: %w", closeErr)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return stagedFile{}, err
+	}
+	return stagedFile{
+		path: f.Name(),
+		size: size,
+		hash: hex.EncodeToString(h.Sum(nil)),
+	}, nil
+}
+
+// object returns a transport object streaming the staged content.
+func (s stagedFile) object(id string, metadata map[string]string) transport.Object {
+	return transport.Object{
+		Id:       id,
+		Metadata: metadata,
+		Size:     s.size,
+		Path:     s.path,
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(s.path)
+		},
+	}
+}
+
+// remove deletes the staged file unless a transport moved it to its destination.
+func (s stagedFile) remove() error {
+	if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// objectHash returns the sha256 sum of the content of obj.
+func objectHash(obj transport.Object) (string, error) {
+	content, _, err := obj.Reader()
+	if err != nil {
+		return "", fmt.Errorf("failed to read content of %s: %w", obj.Id, err)
+	}
+	defer content.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, content); err != nil {
+		return "", fmt.Errorf("failed to read content of %s: %w", obj.Id, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
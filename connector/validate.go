@@ -0,0 +1,55 @@
+package connector
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+	"github.com/myopenfactory/edi-connector/v2/metrics"
+	"github.com/myopenfactory/edi-connector/v2/schedule"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+)
+
+// Validate returns the problems of the processes of cfg: unknown transport
+// types, invalid transport settings and schedules and auth names without
+// credentials in credManager. The remaining settings are validated by
+// config.Validate. No transports are created and no connections established.
+// Without credManager the credentials aren't checked.
+func Validate(cfg config.Config, credManager credentials.CredManager) []config.Problem {
+	var problems []config.Problem
+	credentialErrs := make(map[string]error)
+	index := map[string]int{}
+	for kind, pc := range processes(cfg) {
+		path := fmt.Sprintf("$.%ss[%d]", kind, index[kind])
+		index[kind]++
+
+		if kind == metrics.Outbound {
+			problems = append(problems, transport.ValidateOutbound(path, pc)...)
+		} else {
+			problems = append(problems, transport.ValidateInbound(path, pc)...)
+		}
+		// the interval only matters without cron expression and is validated by config.Validate
+		if _, err := schedule.New(time.Minute, pc.Schedule); err != nil {
+			problems = append(problems, config.Problemf(path+".schedule", "%v", err))
+		}
+
+		if credManager == nil {
+			continue
+		}
+		err, checked := credentialErrs[pc.AuthName]
+		if !checked {
+			_, err = credManager.GetCredential(pc.AuthName)
+			credentialErrs[pc.AuthName] = err
+		}
+		if err == nil {
+			continue
+		}
+		if pc.AuthName == "" {
+			problems = append(problems, config.Problemf(path+".authName", "no default credential found: %v", err))
+		} else {
+			problems = append(problems, config.Problemf(path+".authName", "no credential found for %q: %v", pc.AuthName, err))
+		}
+	}
+	return problems
+}
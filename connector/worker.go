@@ -0,0 +1,279 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"sync"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/metrics"
+	"github.com/myopenfactory/edi-connector/v2/platform"
+	"github.com/myopenfactory/edi-connector/v2/schedule"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+)
+
+// worker runs the processing of a single transport on its own schedule. Runs
+// are executed one after another, so a transport never overlaps with itself.
+type worker struct {
+	kind      string
+	configId  string
+	authName  string
+	process   config.ProcessConfig
+	transport transport.ConfigInfo
+	schedule  *schedule.Schedule
+	run       func(context.Context) error
+	// trigger requests a run before the interval elapsed
+	trigger chan struct{}
+	// stop ends the worker after a run in progress, done is closed once a
+	// started worker ended
+	stop     chan struct{}
+	stopOnce sync.Once
+
+	mu           sync.Mutex
+	done         chan struct{}
+	paused       bool
+	running      bool
+	nextRun      time.Time
+	lastRun      time.Time
+	lastDuration time.Duration
+	lastErr      error
+}
+
+// workerStatus is a snapshot of the state of a worker.
+type workerStatus struct {
+	Kind         string    `json:"kind"`
+	ConfigId     string    `json:"configId"`
+	Paused       bool      `json:"paused"`
+	Running      bool      `json:"running"`
+	NextRun      time.Time `json:"nextRun,omitzero"`
+	LastRun      time.Time `json:"lastRun,omitzero"`
+	LastDuration string    `json:"lastDuration,omitempty"`
+	LastResult   string    `json:"lastResult,omitempty"`
+	LastError    string    `json:"lastError,omitempty"`
+}
+
+func newWorker(kind string, process config.ProcessConfig, t transport.ConfigInfo, schedule *schedule.Schedule, run func(context.Context) error) *worker {
+	return &worker{
+		kind:      kind,
+		configId:  process.Id,
+		authName:  process.AuthName,
+		process:   process,
+		transport: t,
+		schedule:  schedule,
+		run:       run,
+		trigger:   make(chan struct{}, 1),
+		stop:      make(chan struct{}),
+	}
+}
+
+// halt stops w and waits for a run in progress to complete.
+func (w *worker) halt() {
+	w.stopOnce.Do(func() { close(w.stop) })
+	w.mu.Lock()
+	done := w.done
+	w.mu.Unlock()
+	if done != nil {
+		<-done
+	}
+}
+
+// wake requests a run of w without blocking. Requests made while a run is
+// pending are merged.
+func (w *worker) wake() {
+	select {
+	case w.trigger <- struct{}{}:
+	default:
+	}
+}
+
+func (w *worker) status() workerStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	status := workerStatus{
+		Kind:     w.kind,
+		ConfigId: w.configId,
+		Paused:   w.paused,
+		Running:  w.running,
+		NextRun:  w.nextRun,
+		LastRun:  w.lastRun,
+	}
+	if !w.lastRun.IsZero() {
+		status.LastDuration = w.lastDuration.String()
+		status.LastResult = "success"
+		if w.lastErr != nil {
+			status.LastResult = "error"
+			status.LastError = w.lastErr.Error()
+		}
+	}
+	return status
+}
+
+// setPaused pauses or resumes the scheduled and triggered runs of w. A run in
+// progress is completed.
+func (w *worker) setPaused(paused bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.paused = paused
+}
+
+func (w *worker) isPaused() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.paused
+}
+
+// work runs w on its schedule or trigger until ctx is done or w is stopped.
+// Runs due within a blackout window are skipped. The slots of the connector
+// bound the number of transports processed at the same time.
+func (c *Connector) work(ctx context.Context, w *worker) {
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	c.scheduleNext(w, timer)
+	for {
+		select {
+		case <-timer.C:
+			c.scheduleNext(w, timer)
+			// the timer may fire late, e.g. after the system was suspended
+			if end, blocked := w.schedule.Blocked(time.Now()); blocked {
+				c.logger.Debug("skipping scheduled run within blackout", "kind", w.kind, "configId", w.configId, "until", end)
+				continue
+			}
+		case <-w.trigger:
+			if end, blocked := w.schedule.Blocked(time.Now()); blocked {
+				c.logger.Debug("ignoring trigger within blackout", "kind", w.kind, "configId", w.configId, "until", end)
+				continue
+			}
+		case <-w.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+
+		if w.isPaused() {
+			c.logger.Debug("skipping run of paused transport", "kind", w.kind, "configId", w.configId)
+			continue
+		}
+		if until, unavailable := c.platformClient.Unavailable(); unavailable {
+			c.logger.Debug("skipping run while platform is unavailable", "kind", w.kind, "configId", w.configId, "until", until)
+			continue
+		}
+
+		select {
+		case c.slots <- struct{}{}:
+		case <-w.stop:
+			return
+		case <-ctx.Done():
+			return
+		}
+		start := time.Now()
+		w.mu.Lock()
+		w.running = true
+		w.mu.Unlock()
+		err := w.run(ctx)
+		<-c.slots
+		w.mu.Lock()
+		w.running = false
+		w.lastRun = start
+		w.lastDuration = time.Since(start)
+		w.lastErr = err
+		w.mu.Unlock()
+		if errors.Is(err, platform.ErrCircuitOpen) {
+			c.logger.Debug("run of "+w.kind+" transport stopped, platform is unavailable", "configId", w.configId, "error", err)
+			continue
+		}
+		if err != nil {
+			c.logger.Error("error processing "+w.kind+" transport", "configId", w.configId, "error", err)
+			continue
+		}
+		metrics.RunSucceeded(w.kind, w.configId, w.authName)
+		c.logger.Debug("processed "+w.kind+" transport", "configId", w.configId, "duration", time.Since(start))
+	}
+}
+
+// scheduleNext resets timer to the next run of w. The timer is stopped if
+// there is no further run.
+func (c *Connector) scheduleNext(w *worker, timer *time.Timer) {
+	next := w.schedule.Next(time.Now())
+	w.mu.Lock()
+	w.nextRun = next
+	w.mu.Unlock()
+	if next.IsZero() {
+		c.logger.Warn("transport has no further scheduled runs", "kind", w.kind, "configId", w.configId)
+		timer.Stop()
+		return
+	}
+	c.logger.Debug("scheduled next run", "kind", w.kind, "configId", w.configId, "next", next)
+	timer.Reset(time.Until(next))
+}
+
+// forEach calls fn for all items with at most limit calls running at the same
+// time. No further calls are started after a call failed, calls already
+// running are completed. The errors of all failed calls are returned.
+func forEach[T any](ctx context.Context, items []T, limit int, fn func(context.Context, T) error) error {
+	return forEachSeq(ctx, func(yield func(T, error) bool) {
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}, limit, fn)
+}
+
+// forEachSeq is forEach for items yielded by seq. Items are pulled from seq
+// only when a call may be started, an error yielded by seq stops the iteration.
+func forEachSeq[T any](ctx context.Context, seq iter.Seq2[T, error], limit int, fn func(context.Context, T) error) error {
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		errs   []error
+		failed = make(chan struct{})
+		once   sync.Once
+	)
+	fail := func(err error) {
+		mu.Lock()
+		errs = append(errs, err)
+		mu.Unlock()
+		once.Do(func() { close(failed) })
+	}
+
+	slots := make(chan struct{}, max(limit, 1))
+	for item, err := range seq {
+		if err != nil {
+			fail(err)
+			break
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-failed:
+		case <-ctx.Done():
+		}
+		if isClosed(failed) || ctx.Err() != nil {
+			break
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-slots }()
+			if err := fn(ctx, item); err != nil {
+				fail(err)
+			}
+		}()
+	}
+	wg.Wait()
+	if len(errs) == 0 {
+		return ctx.Err()
+	}
+	return errors.Join(errs...)
+}
+
+// isClosed reports whether ch is closed without blocking.
+func isClosed(ch <-chan struct{}) bool {
+	select {
+	case <-ch:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,99 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestForEachLimit(t *testing.T) {
+	var running, peak atomic.Int32
+	err := forEach(context.Background(), slices.Repeat([]int{1}, 20), 3, func(ctx context.Context, _ int) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got: %v", err)
+	}
+	if peak.Load() > 3 {
+		t.Errorf("Expected at most %d concurrent calls, got: %d", 3, peak.Load())
+	}
+}
+
+func TestForEachErrorStopsNewItems(t *testing.T) {
+	errFailed := errors.New("failed")
+	var mu sync.Mutex
+	var started []int
+	err := forEach(context.Background(), []int{1, 2, 3, 4, 5}, 1, func(ctx context.Context, item int) error {
+		mu.Lock()
+		started = append(started, item)
+		mu.Unlock()
+		if item == 2 {
+			return errFailed
+		}
+		return nil
+	})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("Expected error %v, got: %v", errFailed, err)
+	}
+	if !slices.Equal(started, []int{1, 2}) {
+		t.Errorf("Expected items %v to be started, got: %v", []int{1, 2}, started)
+	}
+}
+
+func TestForEachSiblingsFinish(t *testing.T) {
+	errFailed := errors.New("failed")
+	release := make(chan struct{})
+	var finished atomic.Int32
+	err := forEach(context.Background(), []int{1, 2, 3}, 3, func(ctx context.Context, item int) error {
+		if item == 1 {
+			// fail once the siblings are running
+			time.Sleep(10 * time.Millisecond)
+			close(release)
+			return errFailed
+		}
+		<-release
+		// give a cancellation the chance to reach the sibling
+		time.Sleep(10 * time.Millisecond)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		finished.Add(1)
+		return nil
+	})
+	if !errors.Is(err, errFailed) {
+		t.Errorf("Expected error %v, got: %v", errFailed, err)
+	}
+	if finished.Load() != 2 {
+		t.Errorf("Expected %d siblings to finish, got: %d", 2, finished.Load())
+	}
+}
+
+func TestForEachSeqJoinsErrors(t *testing.T) {
+	errList := errors.New("list failed")
+	errItem := errors.New("item failed")
+	seq := func(yield func(int, error) bool) {
+		if !yield(1, nil) {
+			return
+		}
+		yield(0, errList)
+	}
+	err := forEachSeq(context.Background(), seq, 2, func(ctx context.Context, item int) error {
+		return errItem
+	})
+	if !errors.Is(err, errList) || !errors.Is(err, errItem) {
+		t.Errorf("Expected errors %v and %v, got: %v", errList, errItem, err)
+	}
+}
@@ -0,0 +1,303 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+	"golang.org/x/term"
+)
+
+// credentialsCommand manages the credentials of the connector within the
+// credential backend selected by the config file. Without auth name the
+// default credential is used.
+func credentialsCommand(configFile, logLevel string, args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("usage: edi-connector credentials set|get|delete|list|test [authName]")
+	}
+	cmd := newCommand("credentials "+args[1], configFile, logLevel)
+	args = args[2:]
+	switch cmd.flags.Name() {
+	case "credentials set":
+		return setCredential(cmd, args)
+	case "credentials get":
+		return getCredential(cmd, args)
+	case "credentials delete":
+		return deleteCredential(cmd, args)
+	case "credentials list":
+		return listCredentials(cmd, args)
+	case "credentials test":
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		return testCredential(ctx, cmd, args)
+	default:
+		return fmt.Errorf("unknown command: %s", cmd.flags.Name())
+	}
+}
+
+// authNameArg parses args and returns the auth name given as optional positional argument.
+func (cmd *command) authNameArg(args []string, usage string) (string, error) {
+	positional, err := cmd.parseRange(args, usage, 0, 1)
+	if err != nil {
+		return "", err
+	}
+	if len(positional) == 0 {
+		return "", nil
+	}
+	return positional[0], nil
+}
+
+// credManager returns the credential backend selected by the config file.
+func (cmd *command) credManager() (credentials.CredManager, error) {
+	cfg, _, err := config.ReadConfigFromFile(cmd.configFile)
+	if err != nil {
+		return nil, err
+	}
+	credManager, err := credentials.NewFromConfig(cfg.Credentials)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load credentials: %w", err)
+	}
+	return credManager, nil
+}
+
+// credentialName returns the name of the credential as shown to the user.
+func credentialName(authName string) string {
+	if authName == "" {
+		return "(default)"
+	}
+	return authName
+}
+
+// setCredential stores the credential, the password is read from the
+// terminal or the first line of stdin.
+func setCredential(cmd *command, args []string) error {
+	username := cmd.flags.String("username", "", "Username of the credential, prompted for on a terminal.")
+	authName, err := cmd.authNameArg(args, "[--username U] [authName]")
+	if err != nil {
+		return err
+	}
+	credManager, err := cmd.credManager()
+	if err != nil {
+		return err
+	}
+	store, ok := credManager.(credentials.CredStore)
+	if !ok {
+		return fmt.Errorf("credential backend doesn't support storing credentials")
+	}
+
+	// prompts are only shown if the input is a terminal
+	fd := -1
+	if file, ok := cmd.in.(*os.File); ok && term.IsTerminal(int(file.Fd())) {
+		fd = int(file.Fd())
+	}
+	interactive := fd >= 0
+	stdin := bufio.NewReader(cmd.in)
+	if *username == "" {
+		if !interactive {
+			return fmt.Errorf("%s: --username is required if the password is read from stdin", cmd.flags.Name())
+		}
+		fmt.Fprint(os.Stderr, "Username: ")
+		*username, err = readLine(stdin)
+		if err != nil {
+			return err
+		}
+		if *username == "" {
+			return fmt.Errorf("username must not be empty")
+		}
+	}
+
+	var password string
+	if interactive {
+		password, err = promptPassword(fd)
+	} else {
+		password, err = readLine(stdin)
+	}
+	if err != nil {
+		return err
+	}
+	if password == "" {
+		return fmt.Errorf("password must not be empty")
+	}
+
+	if err := store.SetCredential(authName, credentials.PasswordAuth{Username: *username, Password: password}); err != nil {
+		return err
+	}
+	result := struct {
+		AuthName string `json:"authName"`
+		Username string `json:"username"`
+	}{authName, *username}
+	return cmd.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "stored credential %s\n", credentialName(result.AuthName))
+	})
+}
+
+// promptPassword reads the password twice from the terminal fd without echoing it.
+func promptPassword(fd int) (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+	password, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	fmt.Fprint(os.Stderr, "Repeat password: ")
+	confirmation, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	if string(password) != string(confirmation) {
+		return "", fmt.Errorf("passwords don't match")
+	}
+	return string(password), nil
+}
+
+// readLine reads a line from r without the line ending.
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil && (!errors.Is(err, io.EOF) || line == "") {
+		return "", fmt.Errorf("failed to read from stdin: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// getCredential prints the username of the credential, the password is
+// masked unless --show-password is given.
+func getCredential(cmd *command, args []string) error {
+	showPassword := cmd.flags.Bool("show-password", false, "Print the password instead of masking it.")
+	authName, err := cmd.authNameArg(args, "[--show-password] [authName]")
+	if err != nil {
+		return err
+	}
+	credManager, err := cmd.credManager()
+	if err != nil {
+		return err
+	}
+	auth, err := credManager.GetCredential(authName)
+	if err != nil {
+		return fmt.Errorf("credential %s: %w", credentialName(authName), err)
+	}
+	password := strings.Repeat("*", 8)
+	if *showPassword {
+		password = auth.Password
+	}
+	result := struct {
+		AuthName string `json:"authName"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{authName, auth.Username, password}
+	return cmd.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "Name:\t%s\n", credentialName(result.AuthName))
+		fmt.Fprintf(w, "Username:\t%s\n", result.Username)
+		fmt.Fprintf(w, "Password:\t%s\n", result.Password)
+	})
+}
+
+// deleteCredential removes the credential from the credential backend.
+func deleteCredential(cmd *command, args []string) error {
+	authName, err := cmd.authNameArg(args, "[authName]")
+	if err != nil {
+		return err
+	}
+	credManager, err := cmd.credManager()
+	if err != nil {
+		return err
+	}
+	store, ok := credManager.(credentials.CredStore)
+	if !ok {
+		return fmt.Errorf("credential backend doesn't support deleting credentials")
+	}
+	if err := store.DeleteCredential(authName); err != nil {
+		return err
+	}
+	result := struct {
+		AuthName string `json:"authName"`
+	}{authName}
+	return cmd.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "deleted credential %s\n", credentialName(result.AuthName))
+	})
+}
+
+// listCredentials lists the names of the credentials, the default credential
+// has an empty name.
+func listCredentials(cmd *command, args []string) error {
+	if _, err := cmd.parse(args, "", 0); err != nil {
+		return err
+	}
+	credManager, err := cmd.credManager()
+	if err != nil {
+		return err
+	}
+	lister, ok := credManager.(credentials.CredLister)
+	if !ok {
+		return fmt.Errorf("credential backend doesn't support listing credentials")
+	}
+	names, err := lister.ListCredentials()
+	if err != nil {
+		return err
+	}
+	if names == nil {
+		names = []string{}
+	}
+	return cmd.print(names, func(w io.Writer) {
+		for _, name := range names {
+			fmt.Fprintln(w, credentialName(name))
+		}
+	})
+}
+
+// testCredential lists the transmissions of a process with the credential to
+// verify the platform accepts it. Without --config-id the first process using
+// the credential is used.
+func testCredential(ctx context.Context, cmd *command, args []string) error {
+	cmd.flags.StringVar(&cmd.configId, "config-id", "", "Process id on the platform, defaults to the first process with the auth name.")
+	authName, err := cmd.authNameArg(args, "[--config-id X] [authName]")
+	if err != nil {
+		return err
+	}
+	cfg, _, err := config.ReadConfigFromFile(cmd.configFile)
+	if err != nil {
+		return err
+	}
+	if cmd.configId == "" {
+		processes := slices.Concat(cfg.Outbounds, cfg.Inbounds)
+		index := slices.IndexFunc(processes, func(pc config.ProcessConfig) bool {
+			return pc.AuthName == authName
+		})
+		if index < 0 {
+			return fmt.Errorf("no process with credential %s found, use --config-id", credentialName(authName))
+		}
+		cmd.configId = processes[index].Id
+	}
+	credManager, err := credentials.NewFromConfig(cfg.Credentials)
+	if err != nil {
+		return fmt.Errorf("failed to load credentials: %w", err)
+	}
+	if _, err := credManager.GetCredential(authName); err != nil {
+		return fmt.Errorf("credential %s: %w", credentialName(authName), err)
+	}
+
+	client, err := cmd.newClient(cfg, credManager)
+	if err != nil {
+		return err
+	}
+	transmissions, err := client.ListTransmissions(ctx, cmd.configId, authName)
+	if err != nil {
+		return fmt.Errorf("credential %s was rejected: %w", credentialName(authName), err)
+	}
+	result := struct {
+		AuthName      string `json:"authName"`
+		ConfigId      string `json:"configId"`
+		Transmissions int    `json:"transmissions"`
+	}{authName, cmd.configId, len(transmissions)}
+	return cmd.print(result, func(w io.Writer) {
+		fmt.Fprintf(w, "credential %s authenticated for %s, %d transmissions waiting\n", credentialName(result.AuthName), result.ConfigId, result.Transmissions)
+	})
+}
@@ -3,18 +3,51 @@ package credentials
 import (
 	"fmt"
 	"os"
+	"slices"
 	"strings"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
 )
 
 type PasswordAuth struct {
-	Username string
-	Password string
+	Username string `json:"username"`
+	Password string `json:"password"`
 }
 
 type CredManager interface {
 	GetCredential(name string) (*PasswordAuth, error)
 }
 
+// CredStore is implemented by credential managers managing the stored credentials.
+type CredStore interface {
+	SetCredential(name string, auth PasswordAuth) error
+	DeleteCredential(name string) error
+}
+
+// CredLister is implemented by credential managers able to list the names
+// of their credentials. The default credential is listed as empty name.
+type CredLister interface {
+	ListCredentials() ([]string, error)
+}
+
+// NewFromConfig returns the credential manager selected by cfg.
+func NewFromConfig(cfg config.CredentialsConfig) (CredManager, error) {
+	switch cfg.Type {
+	case "":
+		return NewDefaultCredManager(), nil
+	case "ENV":
+		return NewEnvCredManager(), nil
+	case "FILE":
+		m, err := NewFileCredManager(cfg.File, cfg.KeyFile, cfg.PassphraseFile)
+		if err != nil {
+			return nil, err
+		}
+		return m, nil
+	default:
+		return nil, fmt.Errorf("unknown credentials type %q", cfg.Type)
+	}
+}
+
 type envCredManager struct {
 	serviceName string
 }
@@ -26,11 +59,7 @@ func NewEnvCredManager() *envCredManager {
 }
 
 func (m *envCredManager) GetCredential(name string) (*PasswordAuth, error) {
-	envName := m.serviceName
-	if name != "" {
-		envName = m.serviceName + "_" + strings.ToUpper(name)
-	}
-	auth, ok := os.LookupEnv(envName)
+	auth, ok := os.LookupEnv(m.envName(name))
 	if !ok {
 		return nil, fmt.Errorf("failed to load authentication environment variable")
 	}
@@ -45,3 +74,38 @@ func (m *envCredManager) GetCredential(name string) (*PasswordAuth, error) {
 		Password: authElements[1],
 	}, nil
 }
+
+// SetCredential fails, environment variables can't be set permanently.
+func (m *envCredManager) SetCredential(name string, auth PasswordAuth) error {
+	return fmt.Errorf("credentials can't be stored in the environment, set the environment variable %s to 'username:password' instead", m.envName(name))
+}
+
+// DeleteCredential fails, environment variables can't be removed permanently.
+func (m *envCredManager) DeleteCredential(name string) error {
+	return fmt.Errorf("credentials can't be deleted from the environment, unset the environment variable %s instead", m.envName(name))
+}
+
+// ListCredentials lists the names of the credentials found within the
+// environment. The names are reported in lower case, as the original case
+// is lost within the upper case variable names. Any case of a name resolves
+// to the same credential.
+func (m *envCredManager) ListCredentials() ([]string, error) {
+	var names []string
+	for _, env := range os.Environ() {
+		key, _, _ := strings.Cut(env, "=")
+		if key == m.serviceName {
+			names = append(names, "")
+		} else if name, ok := strings.CutPrefix(key, m.serviceName+"_"); ok {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
+func (m *envCredManager) envName(name string) string {
+	if name == "" {
+		return m.serviceName
+	}
+	return m.serviceName + "_" + strings.ToUpper(name)
+}
@@ -0,0 +1,269 @@
+package credentials
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const credentialFileVersion = 1
+
+// keySize is the size of the AES-256 key encrypting the credential file.
+const keySize = 32
+
+// credentialFile is the content of an encrypted credential file.
+type credentialFile struct {
+	Version int `json:"version"`
+	// Salt of the key derived from the passphrase, empty for key files.
+	Salt  []byte `json:"salt,omitempty"`
+	Nonce []byte `json:"nonce"`
+	// Data are the AES-GCM encrypted credentials by name as json.
+	Data []byte `json:"data"`
+}
+
+type fileCredManager struct {
+	path       string
+	key        []byte
+	passphrase []byte
+
+	mu sync.Mutex
+	// salt and derivedKey cache the last key derived from the passphrase.
+	salt       []byte
+	derivedKey []byte
+}
+
+// NewFileCredManager returns a credential manager storing the credentials
+// encrypted within the file at path. The key is read base64 encoded from
+// keyFile or derived from the passphrase within passphraseFile. It fails if
+// the files are accessible by other users or the existing credential file
+// can't be decrypted.
+func NewFileCredManager(path, keyFile, passphraseFile string) (*fileCredManager, error) {
+	if path == "" {
+		return nil, fmt.Errorf("credential file required")
+	}
+	if (keyFile == "") == (passphraseFile == "") {
+		return nil, fmt.Errorf("either a key file or a passphrase file is required for the credential file")
+	}
+	m := &fileCredManager{path: path}
+	if keyFile != "" {
+		data, err := readSecretFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		m.key, err = base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(m.key) != keySize {
+			return nil, fmt.Errorf("key file %s must contain a base64 encoded %d byte key", keyFile, keySize)
+		}
+	} else {
+		data, err := readSecretFile(passphraseFile)
+		if err != nil {
+			return nil, err
+		}
+		m.passphrase = bytes.TrimRight(data, "\r\n")
+		if len(m.passphrase) == 0 {
+			return nil, fmt.Errorf("passphrase file %s is empty", passphraseFile)
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, _, err := m.load(); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+func (m *fileCredManager) GetCredential(name string) (*PasswordAuth, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	credentials, _, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	auth, ok := credentials[name]
+	if !ok {
+		return nil, fmt.Errorf("credential not found within %s", m.path)
+	}
+	return &auth, nil
+}
+
+// SetCredential creates or replaces the credential within the credential file.
+func (m *fileCredManager) SetCredential(name string, auth PasswordAuth) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	credentials, salt, err := m.load()
+	if err != nil {
+		return err
+	}
+	credentials[name] = auth
+	return m.save(credentials, salt)
+}
+
+// DeleteCredential removes the credential from the credential file.
+func (m *fileCredManager) DeleteCredential(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	credentials, salt, err := m.load()
+	if err != nil {
+		return err
+	}
+	if _, ok := credentials[name]; !ok {
+		return fmt.Errorf("credential not found within %s", m.path)
+	}
+	delete(credentials, name)
+	return m.save(credentials, salt)
+}
+
+// ListCredentials lists the names of the credentials within the credential file.
+func (m *fileCredManager) ListCredentials() ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	credentials, _, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	return slices.Sorted(maps.Keys(credentials)), nil
+}
+
+// load decrypts the credentials and returns them with the salt of the key.
+// A missing credential file contains no credentials. The caller must hold m.mu.
+func (m *fileCredManager) load() (map[string]PasswordAuth, []byte, error) {
+	credentials := make(map[string]PasswordAuth)
+	data, err := readSecretFile(m.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return credentials, nil, nil
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	var file credentialFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode credential file %s: %w", m.path, err)
+	}
+	if file.Version != credentialFileVersion {
+		return nil, nil, fmt.Errorf("unsupported version %d of credential file %s", file.Version, m.path)
+	}
+	aead, err := m.cipher(file.Salt)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(file.Nonce) != aead.NonceSize() {
+		return nil, nil, fmt.Errorf("invalid nonce within credential file %s", m.path)
+	}
+	plaintext, err := aead.Open(nil, file.Nonce, file.Data, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to decrypt credential file %s, wrong key or passphrase", m.path)
+	}
+	if err := json.Unmarshal(plaintext, &credentials); err != nil {
+		return nil, nil, fmt.Errorf("failed to decode credentials of %s: %w", m.path, err)
+	}
+	return credentials, file.Salt, nil
+}
+
+// save encrypts the credentials into a temporary file which replaces the
+// credential file, so a crash never leaves a partially written file behind.
+// The caller must hold m.mu.
+func (m *fileCredManager) save(credentials map[string]PasswordAuth, salt []byte) error {
+	if m.passphrase != nil && salt == nil {
+		salt = make([]byte, 16)
+		rand.Read(salt)
+	}
+	aead, err := m.cipher(salt)
+	if err != nil {
+		return err
+	}
+	plaintext, err := json.Marshal(credentials)
+	if err != nil {
+		return fmt.Errorf("failed to encode credentials: %w", err)
+	}
+	nonce := make([]byte, aead.NonceSize())
+	rand.Read(nonce)
+	data, err := json.MarshalIndent(credentialFile{
+		Version: credentialFileVersion,
+		Salt:    salt,
+		Nonce:   nonce,
+		Data:    aead.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode credential file: %w", err)
+	}
+
+	// temporary files are only accessible by the current user
+	tmp, err := os.CreateTemp(filepath.Dir(m.path), filepath.Base(m.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary credential file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write credential file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync credential file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close credential file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), m.path); err != nil {
+		return fmt.Errorf("failed to replace credential file: %w", err)
+	}
+	return nil
+}
+
+// cipher returns the AES-GCM cipher of the key file or the key derived from
+// the passphrase with salt. The caller must hold m.mu.
+func (m *fileCredManager) cipher(salt []byte) (cipher.AEAD, error) {
+	key := m.key
+	if m.passphrase != nil {
+		if len(salt) == 0 {
+			return nil, fmt.Errorf("credential file %s wasn't encrypted with a passphrase", m.path)
+		}
+		if m.derivedKey == nil || !bytes.Equal(m.salt, salt) {
+			derivedKey, err := scrypt.Key(m.passphrase, salt, 1<<15, 8, 1, keySize)
+			if err != nil {
+				return nil, fmt.Errorf("failed to derive key from passphrase: %w", err)
+			}
+			m.salt, m.derivedKey = salt, derivedKey
+		}
+		key = m.derivedKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// readSecretFile reads the file at path, which must not be accessible by
+// other users.
+func readSecretFile(path string) ([]byte, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	// windows doesn't have permission bits, files are protected by ACLs
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o007 != 0 {
+		return nil, fmt.Errorf("%s is accessible by other users (%s), remove their permissions with chmod o-rwx", path, info.Mode().Perm())
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return data, nil
+}
@@ -0,0 +1,115 @@
+package credentials_test
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+)
+
+func writeSecret(t *testing.T, dir, name, content string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("Failed to write %s: %v", name, err)
+	}
+	return path
+}
+
+func TestFileCredManager(t *testing.T) {
+	dir := t.TempDir()
+	credentialFile := filepath.Join(dir, "credentials.json")
+	keyFile := writeSecret(t, dir, "key", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=\n")
+
+	m, err := credentials.NewFileCredManager(credentialFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("Failed to create credential manager: %v", err)
+	}
+	if _, err := m.GetCredential("partner"); err == nil {
+		t.Errorf("Expected error for missing credential")
+	}
+	if err := m.SetCredential("partner", credentials.PasswordAuth{Username: "user", Password: "secret"}); err != nil {
+		t.Fatalf("Failed to store credential: %v", err)
+	}
+	if err := m.SetCredential("", credentials.PasswordAuth{Username: "default", Password: "pass"}); err != nil {
+		t.Fatalf("Failed to store credential: %v", err)
+	}
+
+	data, err := os.ReadFile(credentialFile)
+	if err != nil {
+		t.Fatalf("Failed to read credential file: %v", err)
+	}
+	if strings.Contains(string(data), "secret") {
+		t.Errorf("Expected encrypted credential file, got: %s", data)
+	}
+
+	// a new manager reads the credentials stored by the first
+	m, err = credentials.NewFileCredManager(credentialFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("Failed to create credential manager: %v", err)
+	}
+	auth, err := m.GetCredential("partner")
+	if err != nil || auth.Username != "user" || auth.Password != "secret" {
+		t.Errorf("Expected stored credential, got: %v %v", auth, err)
+	}
+	names, err := m.ListCredentials()
+	if err != nil || !slices.Equal(names, []string{"", "partner"}) {
+		t.Errorf("Expected credentials [ partner], got: %v %v", names, err)
+	}
+	if err := m.DeleteCredential("partner"); err != nil {
+		t.Fatalf("Failed to delete credential: %v", err)
+	}
+	if _, err := m.GetCredential("partner"); err == nil {
+		t.Errorf("Expected error for deleted credential")
+	}
+
+	otherKey := writeSecret(t, dir, "other", "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=")
+	if _, err := credentials.NewFileCredManager(credentialFile, otherKey, ""); err == nil {
+		t.Errorf("Expected error for wrong key")
+	}
+}
+
+func TestFileCredManagerPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	credentialFile := filepath.Join(dir, "credentials.json")
+	passphraseFile := writeSecret(t, dir, "passphrase", "correct horse battery staple\n")
+
+	m, err := credentials.NewFileCredManager(credentialFile, "", passphraseFile)
+	if err != nil {
+		t.Fatalf("Failed to create credential manager: %v", err)
+	}
+	if err := m.SetCredential("partner", credentials.PasswordAuth{Username: "user", Password: "secret"}); err != nil {
+		t.Fatalf("Failed to store credential: %v", err)
+	}
+
+	m, err = credentials.NewFileCredManager(credentialFile, "", passphraseFile)
+	if err != nil {
+		t.Fatalf("Failed to create credential manager: %v", err)
+	}
+	if auth, err := m.GetCredential("partner"); err != nil || auth.Password != "secret" {
+		t.Errorf("Expected stored credential, got: %v %v", auth, err)
+	}
+
+	wrongPassphrase := writeSecret(t, dir, "wrong", "wrong")
+	if _, err := credentials.NewFileCredManager(credentialFile, "", wrongPassphrase); err == nil {
+		t.Errorf("Expected error for wrong passphrase")
+	}
+}
+
+func TestFileCredManagerPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("permission bits aren't checked on windows")
+	}
+	dir := t.TempDir()
+	keyFile := writeSecret(t, dir, "key", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+	if err := os.Chmod(keyFile, 0644); err != nil {
+		t.Fatalf("Failed to change permissions: %v", err)
+	}
+	if _, err := credentials.NewFileCredManager(filepath.Join(dir, "credentials.json"), keyFile, ""); err == nil {
+		t.Errorf("Expected error for world-readable key file")
+	}
+}
@@ -0,0 +1,32 @@
+package credentials_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+)
+
+func TestEnvCredManagerList(t *testing.T) {
+	t.Setenv("EDI_CONNECTOR", "user:password")
+	t.Setenv("EDI_CONNECTOR_SPECIAL", "special:secret")
+	m := credentials.NewEnvCredManager()
+
+	names, err := m.ListCredentials()
+	if err != nil {
+		t.Fatalf("Failed to list credentials: %v", err)
+	}
+	if !slices.Contains(names, "") || !slices.Contains(names, "special") {
+		t.Fatalf("Expected default and lower case special credential, got: %v", names)
+	}
+	// listed names resolve regardless of their case
+	for _, name := range []string{"special", "Special", "SPECIAL"} {
+		auth, err := m.GetCredential(name)
+		if err != nil {
+			t.Fatalf("Failed to get credential %s: %v", name, err)
+		}
+		if auth.Username != "special" {
+			t.Errorf("Expected username special for %s, got: %s", name, auth.Username)
+		}
+	}
+}
@@ -1,7 +1,10 @@
 package credentials
 
 import (
+	"errors"
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/danieljoos/wincred"
 )
@@ -29,6 +32,50 @@ func (m *windowsCredManager) GetCredential(name string) (*PasswordAuth, error) {
 	}, nil
 }
 
+// SetCredential creates or replaces the credential within the windows
+// credential manager, persisted for the local machine.
+func (m *windowsCredManager) SetCredential(name string, auth PasswordAuth) error {
+	credential := wincred.NewGenericCredential(m.generateWindowsCredName(name))
+	credential.UserName = auth.Username
+	credential.CredentialBlob = []byte(auth.Password)
+	credential.Persist = wincred.PersistLocalMachine
+	if err := credential.Write(); err != nil {
+		return fmt.Errorf("failed to store credential: %w", err)
+	}
+	return nil
+}
+
+// DeleteCredential removes the credential from the windows credential manager.
+func (m *windowsCredManager) DeleteCredential(name string) error {
+	credential, err := wincred.GetGenericCredential(m.generateWindowsCredName(name))
+	if err != nil {
+		return fmt.Errorf("failed to retrieve credential: %w", err)
+	}
+	if err := credential.Delete(); err != nil {
+		return fmt.Errorf("failed to delete credential: %w", err)
+	}
+	return nil
+}
+
+// ListCredentials lists the names of the credentials of the connector within
+// the windows credential manager.
+func (m *windowsCredManager) ListCredentials() ([]string, error) {
+	credentials, err := wincred.FilteredList(m.serviceName + "*")
+	if err != nil && !errors.Is(err, wincred.ErrElementNotFound) {
+		return nil, fmt.Errorf("failed to list credentials: %w", err)
+	}
+	var names []string
+	for _, credential := range credentials {
+		if credential.TargetName == m.serviceName {
+			names = append(names, "")
+		} else if name, ok := strings.CutPrefix(credential.TargetName, m.serviceName+"/"); ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names, nil
+}
+
 func (m *windowsCredManager) generateWindowsCredName(name string) string {
 	credName := m.serviceName
 	if name != "" {
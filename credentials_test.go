@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// writeCredentialsConfig writes a config storing the credentials within an
+// encrypted credential file.
+func writeCredentialsConfig(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	keyFile := filepath.Join(dir, "key")
+	key := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{1}, 32))
+	if err := os.WriteFile(keyFile, []byte(key), 0600); err != nil {
+		t.Fatalf("Failed to write key file: %v", err)
+	}
+	configFile := filepath.Join(dir, "config.yaml")
+	content := `
+url: https://example.com
+credentials:
+  type: FILE
+  file: ` + filepath.Join(dir, "credentials") + `
+  keyFile: ` + keyFile + `
+`
+	if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config file: %v", err)
+	}
+	return configFile
+}
+
+func TestReadLine(t *testing.T) {
+	r := bufio.NewReader(strings.NewReader("user\r\npassword\nlast"))
+	for _, expected := range []string{"user", "password", "last"} {
+		line, err := readLine(r)
+		if err != nil {
+			t.Fatalf("Failed to read line %q: %v", expected, err)
+		}
+		if line != expected {
+			t.Errorf("Expected line %q, got: %q", expected, line)
+		}
+	}
+	if _, err := readLine(r); err == nil {
+		t.Error("Expected error reading beyond the input")
+	}
+}
+
+func TestSetCredentialFromStdin(t *testing.T) {
+	configFile := writeCredentialsConfig(t)
+
+	cmd := newCommand("credentials set", configFile, "")
+	cmd.in = strings.NewReader("secret\n")
+	cmd.out = &bytes.Buffer{}
+	if err := setCredential(cmd, []string{"special", "--username", "bob"}); err != nil {
+		t.Fatalf("Failed to set credential: %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd = newCommand("credentials get", configFile, "")
+	cmd.out = &out
+	if err := getCredential(cmd, []string{"special", "--json"}); err != nil {
+		t.Fatalf("Failed to get credential: %v", err)
+	}
+	var result struct {
+		AuthName string `json:"authName"`
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("Expected json object, got %q: %v", out.String(), err)
+	}
+	if result.AuthName != "special" || result.Username != "bob" || result.Password != "********" {
+		t.Errorf("Expected credential with masked password, got: %+v", result)
+	}
+
+	out.Reset()
+	cmd = newCommand("credentials get", configFile, "")
+	cmd.out = &out
+	if err := getCredential(cmd, []string{"--show-password", "--json", "special"}); err != nil {
+		t.Fatalf("Failed to get credential: %v", err)
+	}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("Expected json object, got %q: %v", out.String(), err)
+	}
+	if result.Password != "secret" {
+		t.Errorf("Expected password read from stdin, got: %q", result.Password)
+	}
+}
+
+func TestSetCredentialRequiresUsername(t *testing.T) {
+	configFile := writeCredentialsConfig(t)
+
+	cmd := newCommand("credentials set", configFile, "")
+	cmd.in = strings.NewReader("bob\nsecret\n")
+	cmd.out = &bytes.Buffer{}
+	if err := setCredential(cmd, []string{"special"}); err == nil || !strings.Contains(err.Error(), "--username") {
+		t.Fatalf("Expected error requiring --username, got: %v", err)
+	}
+
+	var out bytes.Buffer
+	cmd = newCommand("credentials list", configFile, "")
+	cmd.out = &out
+	if err := listCredentials(cmd, []string{"--json"}); err != nil {
+		t.Fatalf("Failed to list credentials: %v", err)
+	}
+	var names []string
+	if err := json.Unmarshal(out.Bytes(), &names); err != nil {
+		t.Fatalf("Expected json array, got %q: %v", out.String(), err)
+	}
+	if len(names) != 0 {
+		t.Errorf("Expected no credential to be stored, got: %v", names)
+	}
+}
+
+func TestCredentialTestFindsProcess(t *testing.T) {
+	platform := startPlatform(t)
+	configFile := writePlatformConfig(t, platform.URL)
+
+	var out bytes.Buffer
+	cmd := newCommand("credentials test", configFile, "")
+	cmd.out = &out
+	if err := testCredential(t.Context(), cmd, []string{"special", "--json"}); err != nil {
+		t.Fatalf("Failed to test credential: %v", err)
+	}
+	var result struct {
+		AuthName      string `json:"authName"`
+		ConfigId      string `json:"configId"`
+		Transmissions int    `json:"transmissions"`
+	}
+	if err := json.Unmarshal(out.Bytes(), &result); err != nil {
+		t.Fatalf("Expected json object, got %q: %v", out.String(), err)
+	}
+	if result.AuthName != "special" || result.ConfigId != "in" || result.Transmissions != 2 {
+		t.Errorf("Expected credential tested with process in, got: %+v", result)
+	}
+	if !slices.Equal(platform.usernames, []string{"special"}) {
+		t.Errorf("Expected request with the tested credential, got usernames: %v", platform.usernames)
+	}
+
+	// no process uses the default credential
+	cmd = newCommand("credentials test", configFile, "")
+	cmd.out = &out
+	if err := testCredential(t.Context(), cmd, nil); err == nil || !strings.Contains(err.Error(), "--config-id") {
+		t.Errorf("Expected error without process using the credential, got: %v", err)
+	}
+}
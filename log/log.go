@@ -12,6 +12,28 @@ import (
 	"github.com/myopenfactory/edi-connector/v2/pkg/log/filesystem"
 )
 
+// level is the level of loggers created by NewFromConfig, it can be changed at runtime with SetLevel.
+var level = new(slog.LevelVar)
+
+// Level returns the current level of loggers created by NewFromConfig.
+func Level() slog.Level {
+	return level.Level()
+}
+
+// SetLevel changes the level of loggers created by NewFromConfig.
+func SetLevel(l slog.Level) {
+	level.Set(l)
+}
+
+// ParseLevel parses a level name like DEBUG, INFO, WARN or ERROR.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", s)
+	}
+	return l, nil
+}
+
 func New() *slog.Logger {
 	return slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{
 		Level: slog.LevelInfo,
@@ -19,17 +41,12 @@ func New() *slog.Logger {
 }
 
 func NewFromConfig(cfg config.LogOptions) (*slog.Logger, error) {
-	var parsedLogLevel slog.Leveler
-	switch cfg.Level {
-	case "ERROR":
-		parsedLogLevel = slog.LevelError
-	case "INFO":
-		parsedLogLevel = slog.LevelInfo
-	case "DEBUG":
-		parsedLogLevel = slog.LevelDebug
-	default:
-		parsedLogLevel = slog.LevelInfo
+	l, err := ParseLevel(cfg.Level)
+	if err != nil {
+		l = slog.LevelInfo
 	}
+	level.Set(l)
+	var parsedLogLevel slog.Leveler = level
 
 	var logHandler slog.Handler
 	switch cfg.Type {
@@ -2,26 +2,50 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"os/signal"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/myopenfactory/edi-connector/v2/config"
 	"github.com/myopenfactory/edi-connector/v2/connector"
+	"github.com/myopenfactory/edi-connector/v2/credentials"
 	"github.com/myopenfactory/edi-connector/v2/log"
 	"github.com/myopenfactory/edi-connector/v2/version"
 )
 
-func execute(configFile string, logLevel string) error {
-	cfg, configFile, err := config.ReadConfigFromFile(configFile)
+// loadConfig reads the config from configFile and applies the log level
+// given on the command line. The config is rejected if checkConfig finds
+// any problems in it. It is used on startup and reload alike, so a config
+// the connector started with isn't rejected by a later reload.
+func loadConfig(configFile string, logLevel string) (config.Config, string, error) {
+	cfg, configFile, problems, err := checkConfig(configFile)
 	if err != nil {
-		return err
+		return config.Config{}, "", err
+	}
+	if len(problems) > 0 {
+		errs := make([]error, 0, len(problems))
+		for _, problem := range problems {
+			errs = append(errs, errors.New(problem.String()))
+		}
+		return config.Config{}, "", fmt.Errorf("%d problems found: %w", len(problems), errors.Join(errs...))
 	}
-
 	if logLevel != "" {
 		cfg.Log.Level = logLevel
 	}
+	return cfg, configFile, nil
+}
+
+func execute(configFile string, logLevel string, dryRun bool) error {
+	cfg, configFile, err := loadConfig(configFile, logLevel)
+	if err != nil {
+		return err
+	}
+	cfg.DryRun = dryRun
 
 	logger, err := log.NewFromConfig(cfg.Log)
 	if err != nil {
@@ -54,6 +78,13 @@ func execute(configFile string, logLevel string) error {
 		return fmt.Errorf("failed to create edi-connector: %w", err)
 	}
 
+	reload := make(chan os.Signal, 1)
+	notifyReload(reload)
+	go cl.WatchConfig(ctx, configFile, func() (config.Config, error) {
+		cfg, _, err := loadConfig(configFile, logLevel)
+		return cfg, err
+	}, reload)
+
 	if err := cl.Run(ctx); err != nil {
 		return fmt.Errorf("failed to run edi-connector: %w", err)
 	}
@@ -61,9 +92,59 @@ func execute(configFile string, logLevel string) error {
 	return nil
 }
 
+// healthcheck queries the health endpoints of the connector running with
+// configFile, to be used as container health check.
+func healthcheck(configFile string) error {
+	cfg, _, err := config.ReadConfigFromFile(configFile)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	return connector.CheckHealth(ctx, cfg)
+}
+
+// checkConfig reads the config from configFile and returns all problems
+// found in its settings, processes and credentials. An error is only
+// returned if the file can't be read.
+func checkConfig(configFile string) (config.Config, string, []config.Problem, error) {
+	cfg, configFile, problems, err := config.ValidateFile(configFile)
+	if err != nil {
+		return config.Config{}, "", nil, err
+	}
+	// problems of the credentials config would be reported twice by loading them
+	var credManager credentials.CredManager
+	if !slices.ContainsFunc(problems, func(p config.Problem) bool { return strings.HasPrefix(p.Path, "$.credentials") }) {
+		credManager, err = credentials.NewFromConfig(cfg.Credentials)
+		if err != nil {
+			problems = append(problems, config.Problemf("$.credentials", "%v", err))
+		}
+	}
+	problems = append(problems, connector.Validate(cfg, credManager)...)
+	return cfg, configFile, problems, nil
+}
+
+// validate prints all problems found in the config file and returns an
+// error if there are any.
+func validate(configFile string) error {
+	_, configFile, problems, err := checkConfig(configFile)
+	if err != nil {
+		return err
+	}
+	for _, problem := range problems {
+		fmt.Println(problem)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%s: %d problems found", configFile, len(problems))
+	}
+	fmt.Printf("%s: config is valid\n", configFile)
+	return nil
+}
+
 func main() {
 	configFile := flag.String("config", "", "Config file.")
 	logLevel := flag.String("log_level", "", "Log level.")
+	dryRun := flag.Bool("dry-run", false, "Log the transfers instead of executing them.")
 
 	flag.Parse()
 
@@ -71,6 +152,32 @@ func main() {
 		switch flag.Arg(0) {
 		case "version":
 			fmt.Printf("Version: %s\n", version.Version)
+		case "healthcheck":
+			if err := healthcheck(*configFile); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+			fmt.Println("healthy")
+		case "run":
+			if err := run(*configFile, *logLevel, *dryRun, flag.Args()[1:]); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		case "transmissions", "send", "attachments":
+			if err := platformCommand(*configFile, *logLevel, flag.Args()); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		case "credentials":
+			if err := credentialsCommand(*configFile, *logLevel, flag.Args()); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
+		case "validate":
+			if err := validate(*configFile); err != nil {
+				fmt.Println(err)
+				os.Exit(1)
+			}
 		default:
 			fmt.Printf("Unknown parameter: %s\n", flag.Arg(0))
 		}
@@ -83,7 +190,7 @@ func main() {
 			os.Exit(1)
 		}
 	}
-	if err := execute(*configFile, *logLevel); err != nil {
+	if err := execute(*configFile, *logLevel, *dryRun); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
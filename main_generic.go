@@ -2,7 +2,12 @@
 
 package main
 
-import "fmt"
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
 
 func serviceRun(configFile string, logLevel string) error {
 	return fmt.Errorf("no windows service on linux")
@@ -11,3 +16,8 @@ func serviceRun(configFile string, logLevel string) error {
 func isWindowsService() bool {
 	return false
 }
+
+// notifyReload relays SIGHUP to c to reload the config.
+func notifyReload(c chan<- os.Signal) {
+	signal.Notify(c, syscall.SIGHUP)
+}
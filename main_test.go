@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadConfigRejectsProblems(t *testing.T) {
+	t.Setenv("EDI_CONNECTOR", "user:password")
+	messageDir := t.TempDir()
+	errorDir := t.TempDir()
+	configFile := filepath.Join(t.TempDir(), "config.yaml")
+	write := func(content string) {
+		t.Helper()
+		if err := os.WriteFile(configFile, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write config file: %v", err)
+		}
+	}
+	valid := `
+url: https://example.com
+outbounds:
+- id: out
+  type: FILE
+  settings:
+    message:
+      path: ` + messageDir + `
+      extensions: [txt]
+    errorPath: ` + errorDir + `
+`
+	write(valid)
+	cfg, _, err := loadConfig(configFile, "DEBUG")
+	if err != nil {
+		t.Fatalf("Failed to load valid config: %v", err)
+	}
+	if cfg.Log.Level != "DEBUG" || len(cfg.Outbounds) != 1 {
+		t.Errorf("Expected config with log level and outbound, got: %+v", cfg)
+	}
+
+	for name, content := range map[string]string{
+		"unknown field":  valid + "unknown: true\n",
+		"bad duration":   valid + "runWaitTime: 5x\n",
+		"missing folder": strings.ReplaceAll(valid, errorDir, filepath.Join(errorDir, "missing")),
+	} {
+		write(content)
+		if _, _, err := loadConfig(configFile, ""); err == nil {
+			t.Errorf("%s: expected config to be rejected", name)
+		}
+	}
+}
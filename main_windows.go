@@ -3,6 +3,7 @@ package main
 import (
 	"context"
 	"fmt"
+	"os"
 	"runtime/debug"
 	"time"
 
@@ -24,6 +25,9 @@ func serviceRun(configFile string, logLevel string) error {
 	return nil
 }
 
+// notifyReload does nothing, there is no reload signal on windows.
+func notifyReload(c chan<- os.Signal) {}
+
 func isWindowsService() bool {
 	ok, err := svc.IsWindowsService()
 	if err != nil {
@@ -40,17 +44,13 @@ type service struct {
 func (s *service) Execute(args []string, r <-chan svc.ChangeRequest, status chan<- svc.Status) (bool, uint32) {
 	status <- svc.Status{State: svc.StartPending}
 
-	cfg, configFile, err := config.ReadConfigFromFile(s.configFile)
+	cfg, configFile, err := loadConfig(s.configFile, s.logLevel)
 	if err != nil {
 		status <- svc.Status{State: svc.StopPending}
 		fmt.Printf("failed to load configfile: %v\n", err)
 		return false, 1
 	}
 
-	if s.logLevel != "" {
-		cfg.Log.Level = s.logLevel
-	}
-
 	logger, err := log.NewFromConfig(cfg.Log)
 	if err != nil {
 		status <- svc.Status{State: svc.StopPending}
@@ -68,6 +68,10 @@ func (s *service) Execute(args []string, r <-chan svc.ChangeRequest, status chan
 		return false, 1
 	}
 	ctx, cancel := context.WithCancel(context.Background())
+	go connector.WatchConfig(ctx, configFile, func() (config.Config, error) {
+		cfg, _, err := loadConfig(configFile, s.logLevel)
+		return cfg, err
+	}, nil)
 	go func() {
 		defer func() {
 			if r := recover(); r != nil {
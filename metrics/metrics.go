@@ -0,0 +1,118 @@
+// Package metrics exposes Prometheus metrics about the transfers of the connector.
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "edi_connector"
+
+// Directions of transfers.
+const (
+	Inbound  = "inbound"
+	Outbound = "outbound"
+)
+
+// Kinds of transferred objects.
+const (
+	Message    = "message"
+	Attachment = "attachment"
+)
+
+// Phase is the processing step a failure occurred in.
+type Phase string
+
+const (
+	PhaseList     Phase = "list"
+	PhaseUpload   Phase = "upload"
+	PhaseFinalize Phase = "finalize"
+	PhaseDownload Phase = "download"
+	PhaseProcess  Phase = "process"
+	PhaseConfirm  Phase = "confirm"
+)
+
+var (
+	registry = prometheus.NewRegistry()
+
+	transfers = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transfers_total",
+		Help:      "Number of messages and attachments uploaded and downloaded.",
+	}, []string{"direction", "kind", "config_id", "auth_name"})
+
+	transferredBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "transferred_bytes_total",
+		Help:      "Number of bytes of messages and attachments uploaded and downloaded.",
+	}, []string{"direction", "kind", "config_id", "auth_name"})
+
+	failures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "failures_total",
+		Help:      "Number of failed transfers by the phase they failed in.",
+	}, []string{"phase", "config_id", "auth_name"})
+
+	platformRequests = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "platform_request_duration_seconds",
+		Help:      "Latency of requests to the platform by endpoint and status, failed connections have the status error.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"endpoint", "method", "status"})
+
+	lastSuccess = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "last_success_timestamp_seconds",
+		Help:      "Unix time of the last successful run of a transport.",
+	}, []string{"direction", "config_id", "auth_name"})
+)
+
+func init() {
+	registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		transfers,
+		transferredBytes,
+		failures,
+		platformRequests,
+		lastSuccess,
+	)
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(registry, promhttp.HandlerOpts{})
+}
+
+// Transferred counts an object of kind with size bytes transferred in direction.
+func Transferred(direction, kind, configId, authName string, size int64) {
+	transfers.WithLabelValues(direction, kind, configId, authName).Inc()
+	if size > 0 {
+		transferredBytes.WithLabelValues(direction, kind, configId, authName).Add(float64(size))
+	}
+}
+
+// Failed counts a transfer failed in phase.
+func Failed(phase Phase, configId, authName string) {
+	failures.WithLabelValues(string(phase), configId, authName).Inc()
+}
+
+// PlatformRequest observes the duration of a request to endpoint answered
+// with status, a status of 0 marks a failed connection.
+func PlatformRequest(endpoint, method string, status int, d time.Duration) {
+	label := "error"
+	if status != 0 {
+		label = strconv.Itoa(status)
+	}
+	platformRequests.WithLabelValues(endpoint, method, label).Observe(d.Seconds())
+}
+
+// RunSucceeded records the time of the last successful run of a transport.
+func RunSucceeded(direction, configId, authName string) {
+	lastSuccess.WithLabelValues(direction, configId, authName).SetToCurrentTime()
+}
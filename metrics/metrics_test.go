@@ -0,0 +1,41 @@
+package metrics_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/metrics"
+)
+
+func TestHandler(t *testing.T) {
+	metrics.Transferred(metrics.Outbound, metrics.Message, "4711", "auth", 42)
+	metrics.Failed(metrics.PhaseUpload, "4711", "auth")
+	metrics.PlatformRequest("listTransmissions", http.MethodGet, http.StatusOK, 10*time.Millisecond)
+	metrics.PlatformRequest("listTransmissions", http.MethodGet, 0, 10*time.Millisecond)
+	metrics.RunSucceeded(metrics.Outbound, "4711", "auth")
+
+	rec := httptest.NewRecorder()
+	metrics.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got: %d", http.StatusOK, rec.Code)
+	}
+	body, _ := io.ReadAll(rec.Body)
+
+	expected := []string{
+		`edi_connector_transfers_total{auth_name="auth",config_id="4711",direction="outbound",kind="message"} 1`,
+		`edi_connector_transferred_bytes_total{auth_name="auth",config_id="4711",direction="outbound",kind="message"} 42`,
+		`edi_connector_failures_total{auth_name="auth",config_id="4711",phase="upload"} 1`,
+		`edi_connector_platform_request_duration_seconds_count{endpoint="listTransmissions",method="GET",status="200"} 1`,
+		`edi_connector_platform_request_duration_seconds_count{endpoint="listTransmissions",method="GET",status="error"} 1`,
+		`edi_connector_last_success_timestamp_seconds{auth_name="auth",config_id="4711",direction="outbound"}`,
+	}
+	for _, line := range expected {
+		if !strings.Contains(string(body), line) {
+			t.Errorf("Expected metrics to contain %s, got: %s", line, body)
+		}
+	}
+}
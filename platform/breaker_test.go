@@ -0,0 +1,33 @@
+package platform
+
+import (
+	"errors"
+	"io"
+	"log/slog"
+	"testing"
+	"time"
+)
+
+func TestBreakerReleaseTrial(t *testing.T) {
+	b := &breaker{
+		logger:   slog.New(slog.NewTextHandler(io.Discard, nil)),
+		state:    breakerOpen,
+		openedAt: time.Now().Add(-breakerCooldown),
+	}
+	if err := b.allow(); err != nil {
+		t.Fatalf("Expected trial call after cooldown, got: %v", err)
+	}
+	if err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected error: %v while trial is pending, got: %v", ErrCircuitOpen, err)
+	}
+
+	// a trial call without outcome lets the next call through as trial
+	b.release()
+	if err := b.allow(); err != nil {
+		t.Errorf("Expected trial call after release, got: %v", err)
+	}
+	b.record(true)
+	if b.state != breakerClosed {
+		t.Errorf("Expected closed circuit after successful trial, got state: %d", b.state)
+	}
+}
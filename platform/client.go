@@ -3,18 +3,30 @@ package platform
 import (
 	"bytes"
 	"context"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
 	"crypto/tls"
 	"crypto/x509"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"os"
 	"runtime"
+	"strings"
+	"time"
 
 	"github.com/myopenfactory/edi-connector/v2/credentials"
 	"github.com/myopenfactory/edi-connector/v2/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
 )
 
 type MessageAttachment struct {
@@ -34,14 +46,54 @@ type Transmission struct {
 	MessageIds []string          `json:"messageids"`
 }
 
+// ErrHashMismatch is returned if downloaded data does not match the hash announced by the platform.
+var ErrHashMismatch = errors.New("hash mismatch")
+
+// hasher returns the hash function announced for the transmission, or nil if it has no hash sum.
+func (t Transmission) hasher() (hash.Hash, error) {
+	if t.Hash.Sum == "" {
+		return nil, nil
+	}
+	return newHash(t.Hash.Method)
+}
+
+// verifySum compares the sum of h with the hash sum of the transmission.
+func (t Transmission) verifySum(h hash.Hash) error {
+	sum := hex.EncodeToString(h.Sum(nil))
+	if !strings.EqualFold(sum, t.Hash.Sum) {
+		return fmt.Errorf("%w: transmission %s: expected %s sum %s, got %s", ErrHashMismatch, t.Id, t.Hash.Method, t.Hash.Sum, sum)
+	}
+	return nil
+}
+
+// newHash returns the hash function for method, e.g. "SHA-256" or "sha256".
+func newHash(method string) (hash.Hash, error) {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", "_", "").Replace(method))
+	switch normalized {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "md5":
+		return md5.New(), nil
+	default:
+		return nil, fmt.Errorf("unsupported hash method: %q", method)
+	}
+}
+
 type Client struct {
+	logger            *slog.Logger
 	http              *http.Client
 	baseUrl           string
 	authCache         map[string]*credentials.PasswordAuth
 	credentialManager credentials.CredManager
+	retry             RetryPolicy
+	breaker           *breaker
 }
 
-func NewClient(baseUrl string, caFile string, credManager credentials.CredManager, proxy string) (*Client, error) {
+func NewClient(logger *slog.Logger, baseUrl string, caFile string, credManager credentials.CredManager, proxy string) (*Client, error) {
 	httpTransport := http.DefaultTransport
 	if proxy != "" {
 		url, err := url.Parse(proxy)
@@ -64,10 +116,13 @@ func NewClient(baseUrl string, caFile string, credManager credentials.CredManage
 	}
 
 	c := &Client{
+		logger:            logger,
 		http:              httpClient,
 		baseUrl:           baseUrl,
 		authCache:         make(map[string]*credentials.PasswordAuth),
 		credentialManager: credManager,
+		retry:             DefaultRetryPolicy,
+		breaker:           breakerFor(logger, baseUrl),
 	}
 
 	if caFile != "" {
@@ -95,36 +150,60 @@ func (c *Client) setAuth(authName string, r *http.Request) error {
 	return nil
 }
 
-func (c *Client) DownloadTransmission(transmission Transmission, authName string) ([]byte, error) {
+func (c *Client) DownloadTransmission(ctx context.Context, transmission Transmission, authName string) ([]byte, error) {
+	var buffer bytes.Buffer
+	if _, err := c.DownloadTransmissionTo(ctx, transmission, authName, &buffer); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+// DownloadTransmissionTo streams the content of transmission into w and
+// returns the number of bytes written. The content is verified against the
+// hash of the transmission after it has been written, so w must be discarded
+// if ErrHashMismatch is returned.
+func (c *Client) DownloadTransmissionTo(ctx context.Context, transmission Transmission, authName string, w io.Writer) (int64, error) {
 	url := transmission.Url
+	h, err := transmission.hasher()
+	if err != nil {
+		return 0, err
+	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create download transmission request: %w", err)
+		return 0, fmt.Errorf("failed to create download transmission request: %w", err)
 	}
 	if err = c.setAuth(authName, req); err != nil {
-		return nil, err
+		return 0, err
 	}
 
-	resp, err := c.http.Do(req)
+	resp, err := c.do(req, "downloadTransmission", true)
 	if err != nil {
-		return nil, fmt.Errorf("error while loading transmission with url %q: %w", url, err)
+		return 0, fmt.Errorf("error while loading transmission with url %q: %w", url, err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("error bad response for transmission %q: %s", url, resp.Status)
+		return 0, fmt.Errorf("error bad response for transmission %q: %s", url, resp.Status)
 	}
 
-	data, err := io.ReadAll(resp.Body)
+	if h != nil {
+		w = io.MultiWriter(w, h)
+	}
+	n, err := io.Copy(w, resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("error while writing response data for transmission %q: %w", url, err)
+		return n, fmt.Errorf("error while writing response data for transmission %q: %w", url, err)
 	}
-	return data, nil
+	if h != nil {
+		if err := transmission.verifySum(h); err != nil {
+			return n, err
+		}
+	}
+	return n, nil
 }
 
 func (c *Client) ListTransmissions(ctx context.Context, configId, authName string) ([]Transmission, error) {
-	req, err := c.req("GET", fmt.Sprintf("/v2/transmissions?configID=%s", configId), nil)
+	req, err := c.req(ctx, "GET", fmt.Sprintf("/v2/transmissions?configID=%s", configId), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create list transmissions request: %w", err)
 	}
@@ -132,7 +211,7 @@ func (c *Client) ListTransmissions(ctx context.Context, configId, authName strin
 		return nil, err
 	}
 
-	res, err := c.http.Do(req)
+	res, err := c.do(req, "listTransmissions", true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list transmisions: %w", err)
 	}
@@ -157,7 +236,12 @@ func (c *Client) ListTransmissions(ctx context.Context, configId, authName strin
 }
 
 func (c *Client) AddTransmission(ctx context.Context, configId, authName string, data []byte) error {
-	req, err := c.req("POST", fmt.Sprintf("/v2/transmissions?configID=%s", configId), data)
+	return c.AddTransmissionStream(ctx, configId, authName, bytes.NewReader(data), int64(len(data)))
+}
+
+// AddTransmissionStream uploads a transmission of size bytes read from content.
+func (c *Client) AddTransmissionStream(ctx context.Context, configId, authName string, content io.Reader, size int64) error {
+	req, err := c.streamReq(ctx, "POST", fmt.Sprintf("/v2/transmissions?configID=%s", configId), content, size)
 	if err != nil {
 		return fmt.Errorf("failed to create add transmission request: %w", err)
 	}
@@ -165,13 +249,13 @@ func (c *Client) AddTransmission(ctx context.Context, configId, authName string,
 		return err
 	}
 
-	res, err := c.http.Do(req)
+	res, err := c.do(req, "addTransmission", false)
 	if err != nil {
 		return fmt.Errorf("failed to add transmission: %w", err)
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		defer res.Body.Close()
 		data, err := io.ReadAll(res.Body)
 		if err != nil {
 			return fmt.Errorf("failed to read response body: %w", err)
@@ -182,19 +266,31 @@ func (c *Client) AddTransmission(ctx context.Context, configId, authName string,
 	return nil
 }
 
+// ConfirmTransmission confirms the successful processing of a transmission.
 func (c *Client) ConfirmTransmission(ctx context.Context, id, authName, status string) error {
+	return c.confirmTransmission(ctx, id, authName, status, false)
+}
+
+// ConfirmTransmissionError confirms a transmission as failed, so it isn't
+// offered again and the failure becomes visible on the platform.
+func (c *Client) ConfirmTransmissionError(ctx context.Context, id, authName, message string) error {
+	return c.confirmTransmission(ctx, id, authName, message, true)
+}
+
+func (c *Client) confirmTransmission(ctx context.Context, id, authName, message string, isError bool) error {
 	var confirmRequest struct {
 		Error   bool   `json:"error"`
 		Message string `json:"message"`
 	}
-	confirmRequest.Message = status
+	confirmRequest.Error = isError
+	confirmRequest.Message = message
 
 	data, err := json.Marshal(confirmRequest)
 	if err != nil {
 		return fmt.Errorf("failed to confirm transmission: %w", err)
 	}
 
-	req, err := c.req("POST", fmt.Sprintf("/v2/transmissions/%s/confirm", id), data)
+	req, err := c.req(ctx, "POST", fmt.Sprintf("/v2/transmissions/%s/confirm", id), data)
 	if err != nil {
 		return fmt.Errorf("failed to create confirm request: %w", err)
 	}
@@ -203,7 +299,7 @@ func (c *Client) ConfirmTransmission(ctx context.Context, id, authName, status s
 		return err
 	}
 
-	res, err := c.http.Do(req)
+	res, err := c.do(req, "confirmTransmission", true)
 	if err != nil {
 		return fmt.Errorf("failed to confirm transmission: %w", err)
 	}
@@ -221,7 +317,12 @@ func (c *Client) ConfirmTransmission(ctx context.Context, id, authName, status s
 }
 
 func (c *Client) AddAttachment(ctx context.Context, data []byte, filename, authName string) error {
-	req, err := c.req("POST", "/v2/attachments", data)
+	return c.AddAttachmentStream(ctx, bytes.NewReader(data), int64(len(data)), filename, authName)
+}
+
+// AddAttachmentStream uploads an attachment of size bytes read from content.
+func (c *Client) AddAttachmentStream(ctx context.Context, content io.Reader, size int64, filename, authName string) error {
+	req, err := c.streamReq(ctx, "POST", "/v2/attachments", content, size)
 	if err != nil {
 		return fmt.Errorf("failed to create attachment upload request: %w", err)
 	}
@@ -230,13 +331,13 @@ func (c *Client) AddAttachment(ctx context.Context, data []byte, filename, authN
 	if err = c.setAuth(authName, req); err != nil {
 		return err
 	}
-	res, err := c.http.Do(req)
+	res, err := c.do(req, "addAttachment", false)
 	if err != nil {
 		return fmt.Errorf("failed issue to attachment upload request: %w", err)
 	}
+	defer res.Body.Close()
 
 	if res.StatusCode != http.StatusOK {
-		defer res.Body.Close()
 		data, err := io.ReadAll(res.Body)
 		if err != nil {
 			return fmt.Errorf("failed to read response body: %w", err)
@@ -248,7 +349,7 @@ func (c *Client) AddAttachment(ctx context.Context, data []byte, filename, authN
 }
 
 func (c *Client) ListMessageAttachments(ctx context.Context, id, authName string) ([]MessageAttachment, error) {
-	req, err := c.req("GET", fmt.Sprintf("/v2/messages/%s/attachments", id), nil)
+	req, err := c.req(ctx, "GET", fmt.Sprintf("/v2/messages/%s/attachments", id), nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch attachments: %w", err)
 	}
@@ -256,7 +357,7 @@ func (c *Client) ListMessageAttachments(ctx context.Context, id, authName string
 		return nil, err
 	}
 
-	res, err := c.http.Do(req)
+	res, err := c.do(req, "listMessageAttachments", true)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create list message attachments request: %w", err)
 	}
@@ -279,7 +380,7 @@ func (c *Client) ListMessageAttachments(ctx context.Context, id, authName string
 	return attachments, nil
 }
 
-func (c *Client) req(method string, path string, data []byte) (*http.Request, error) {
+func (c *Client) req(ctx context.Context, method string, path string, data []byte) (*http.Request, error) {
 	var req *http.Request
 	var err error
 
@@ -287,10 +388,33 @@ func (c *Client) req(method string, path string, data []byte) (*http.Request, er
 	if data != nil {
 		reader = bytes.NewReader(data)
 	}
-	req, err = http.NewRequest(method, fmt.Sprintf("%s%s", c.baseUrl, path), reader)
+	req, err = http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", c.baseUrl, path), reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request for %s:%s: %w", method, path, err)
+	}
+	return req, nil
+}
+
+// streamReq creates a request sending size bytes read from body. The body can
+// only be sent again on retries if it implements io.Seeker.
+func (c *Client) streamReq(ctx context.Context, method string, path string, body io.Reader, size int64) (*http.Request, error) {
+	// the caller owns body, it must not be closed by the http client
+	req, err := http.NewRequestWithContext(ctx, method, fmt.Sprintf("%s%s", c.baseUrl, path), io.NopCloser(body))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create request for %s:%s: %w", method, path, err)
 	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	if seeker, ok := body.(io.Seeker); ok {
+		req.GetBody = func() (io.ReadCloser, error) {
+			if _, err := seeker.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return io.NopCloser(body), nil
+		}
+	}
 	return req, nil
 }
 
@@ -303,5 +427,27 @@ type clientTransport struct {
 func (t *clientTransport) RoundTrip(r *http.Request) (*http.Response, error) {
 	r.Header.Set("User-Agent", t.id)
 	r.Header.Set("Accept", "application/json")
+	otel.GetTextMapPropagator().Inject(r.Context(), propagation.HeaderCarrier(r.Header))
 	return t.transport.RoundTrip(r)
 }
+
+// Ping checks the platform is reachable. Any response besides a server error
+// counts as reachable, credentials aren't verified.
+func (c *Client) Ping(ctx context.Context) error {
+	if until, unavailable := c.Unavailable(); unavailable {
+		return fmt.Errorf("%w: retrying after %s", ErrCircuitOpen, until.Format(time.RFC3339))
+	}
+	req, err := c.req(ctx, "GET", "/", nil)
+	if err != nil {
+		return fmt.Errorf("failed to create ping request: %w", err)
+	}
+	res, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("platform not reachable: %w", err)
+	}
+	res.Body.Close()
+	if res.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("platform not available: %s", res.Status)
+	}
+	return nil
+}
@@ -4,8 +4,10 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"log/slog"
 	"mime"
 	"net/http"
 	"net/http/httptest"
@@ -13,10 +15,16 @@ import (
 	"runtime"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/myopenfactory/edi-connector/v2/config"
 	"github.com/myopenfactory/edi-connector/v2/credentials"
 	"github.com/myopenfactory/edi-connector/v2/platform"
+	"github.com/myopenfactory/edi-connector/v2/tracing"
 	"github.com/myopenfactory/edi-connector/v2/version"
+	"go.opentelemetry.io/otel"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace/noop"
 )
 
 const (
@@ -47,7 +55,7 @@ func TestUsernamePassword(t *testing.T) {
 	defer server.Close()
 	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
 	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
-	cl, err := platform.NewClient(server.URL, "", credentials.NewEnvCredManager(), "")
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
 	if err != nil {
 		t.Errorf("failed to create edi client: %v", err)
 	}
@@ -84,7 +92,7 @@ func TestUserAgent(t *testing.T) {
 
 	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
 	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
-	cl, err := platform.NewClient(server.URL, "", credentials.NewEnvCredManager(), "")
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
 	if err != nil {
 		t.Errorf("failed to create edi client: %v", err)
 	}
@@ -110,7 +118,7 @@ func TestAccept(t *testing.T) {
 
 	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
 	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
-	cl, err := platform.NewClient(server.URL, "", credentials.NewEnvCredManager(), "")
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
 	if err != nil {
 		t.Errorf("failed to create edi client: %v", err)
 	}
@@ -121,6 +129,41 @@ func TestAccept(t *testing.T) {
 	}
 }
 
+func TestTraceContext(t *testing.T) {
+	if _, err := tracing.Setup(context.TODO(), config.TracingConfig{}); err != nil {
+		t.Fatalf("failed to setup tracing: %v", err)
+	}
+	provider := sdktrace.NewTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	ctx, span := tracing.Start(context.TODO(), "test")
+	defer span.End()
+	traceId := span.SpanContext().TraceID().String()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Traceparent"), traceId) {
+			t.Errorf("Expected traceparent header with trace %s, got: %s", traceId, r.Header.Get("Traceparent"))
+		}
+		w.WriteHeader(http.StatusOK)
+		if err := json.NewEncoder(w).Encode(platform.Transmission{}); err != nil {
+			t.Errorf("failed to encode response: %v", err)
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
+	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
+	if err != nil {
+		t.Fatalf("failed to create edi client: %v", err)
+	}
+
+	if _, err := cl.ListTransmissions(ctx, "1", ""); err != nil {
+		t.Errorf("failed to list transmissions: %v", err)
+	}
+}
+
 func TestDownloadTransmission(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.WriteHeader(http.StatusOK)
@@ -130,12 +173,12 @@ func TestDownloadTransmission(t *testing.T) {
 
 	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
 	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
-	cl, err := platform.NewClient(server.URL, "", credentials.NewEnvCredManager(), "")
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
 	if err != nil {
 		t.Errorf("failed to create edi client: %v", err)
 	}
 
-	data, err := cl.DownloadTransmission(platform.Transmission{
+	data, err := cl.DownloadTransmission(t.Context(), platform.Transmission{
 		Id:  "1",
 		Url: server.URL,
 	}, "")
@@ -173,7 +216,7 @@ func TestListTransmissions(t *testing.T) {
 
 	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
 	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
-	cl, err := platform.NewClient(server.URL, "", credentials.NewEnvCredManager(), "")
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
 	if err != nil {
 		t.Errorf("failed to create edi client: %v", err)
 	}
@@ -229,7 +272,7 @@ func TestAddTransmission(t *testing.T) {
 
 	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
 	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
-	cl, err := platform.NewClient(server.URL, "", credentials.NewEnvCredManager(), "")
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
 	if err != nil {
 		t.Errorf("failed to create edi client: %v", err)
 	}
@@ -270,7 +313,7 @@ func TestConfirmTransmission(t *testing.T) {
 
 	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
 	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
-	cl, err := platform.NewClient(server.URL, "", credentials.NewEnvCredManager(), "")
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
 	if err != nil {
 		t.Errorf("failed to create edi client: %v", err)
 	}
@@ -331,7 +374,7 @@ func TestAddAttachment(t *testing.T) {
 
 	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
 	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
-	cl, err := platform.NewClient(server.URL, "", credentials.NewEnvCredManager(), "")
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
 	if err != nil {
 		t.Errorf("failed to create edi client: %v", err)
 	}
@@ -366,7 +409,7 @@ func TestListMessageAttachments(t *testing.T) {
 
 	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
 	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
-	cl, err := platform.NewClient(server.URL, "", credentials.NewEnvCredManager(), "")
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
 	if err != nil {
 		t.Errorf("failed to create edi client: %v", err)
 	}
@@ -391,3 +434,136 @@ func TestListMessageAttachments(t *testing.T) {
 		t.Errorf("Expected attachment item id: %s, got: %s", expectedItemId, attachment.ItemId)
 	}
 }
+
+func TestDownloadTransmissionVerifiesHash(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("data"))
+	}))
+	defer server.Close()
+
+	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
+	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
+	if err != nil {
+		t.Errorf("failed to create edi client: %v", err)
+	}
+
+	tests := []struct {
+		method string
+		sum    string
+		err    error
+	}{
+		{method: "SHA-256", sum: "3a6eb0790f39ac87c94f3856b2dd2c5d110e6811602261a9a923d3bb23adc8b7"},
+		{method: "sha1", sum: "A17C9AAA61E80A1BF71D0D850AF4E5BAA9800BBD"},
+		{method: "MD5", sum: "8d777f385d3dfec8815d20f7496026dc"},
+		{method: "SHA-256", sum: "0000000000000000000000000000000000000000000000000000000000000000", err: platform.ErrHashMismatch},
+	}
+	for _, test := range tests {
+		transmission := platform.Transmission{
+			Id:  "1",
+			Url: server.URL,
+		}
+		transmission.Hash.Method = test.method
+		transmission.Hash.Sum = test.sum
+		_, err := cl.DownloadTransmission(t.Context(), transmission, "")
+		if test.err == nil && err != nil {
+			t.Errorf("failed to download transmission with %s hash: %v", test.method, err)
+		}
+		if test.err != nil && !errors.Is(err, test.err) {
+			t.Errorf("Expected error: %v, got: %v", test.err, err)
+		}
+	}
+}
+
+func TestConfirmTransmissionError(t *testing.T) {
+	transmissionId := "123515"
+	testData := []byte(`{"error":true,"message":"failed"}`)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		expectedPath := fmt.Sprintf("/v2/transmissions/%s/confirm", transmissionId)
+		if expectedPath != r.URL.Path {
+			t.Errorf("Expected request path: %s, got: %s", expectedPath, r.URL.Path)
+		}
+		defer r.Body.Close()
+		gotData, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read request body: %v", err)
+		}
+		if !bytes.Equal(testData, gotData) {
+			t.Errorf("Expected request data: %s, got: %s", testData, gotData)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
+	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
+	if err != nil {
+		t.Errorf("failed to create edi client: %v", err)
+	}
+
+	err = cl.ConfirmTransmissionError(t.Context(), transmissionId, "", "failed")
+	if err != nil {
+		t.Errorf("failed to confirm transmission error: %v", err)
+	}
+}
+
+func TestDownloadTransmissionCancel(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
+	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
+	if err != nil {
+		t.Fatalf("failed to create edi client: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(t.Context(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = cl.DownloadTransmission(ctx, platform.Transmission{Id: "1", Url: server.URL}, "")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Expected error: %v, got: %v", context.DeadlineExceeded, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Expected download to be cancelled, took: %v", elapsed)
+	}
+}
+
+func TestAddTransmissionStream(t *testing.T) {
+	testData := []byte("streamed content")
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ContentLength != int64(len(testData)) {
+			t.Errorf("Expected content length: %d, got: %d", len(testData), r.ContentLength)
+		}
+		gotData, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("failed to read request body: %v", err)
+		}
+		if !bytes.Equal(testData, gotData) {
+			t.Errorf("Expected request data: %s, got: %s", testData, gotData)
+		}
+	}))
+	defer server.Close()
+
+	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
+	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), server.URL, "", credentials.NewEnvCredManager(), "")
+	if err != nil {
+		t.Fatalf("failed to create edi client: %v", err)
+	}
+
+	err = cl.AddTransmissionStream(t.Context(), "1", "", io.MultiReader(bytes.NewReader(testData)), int64(len(testData)))
+	if err != nil {
+		t.Errorf("failed to add transmission: %v", err)
+	}
+}
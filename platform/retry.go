@@ -0,0 +1,261 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/metrics"
+)
+
+// ErrCircuitOpen is returned without contacting the platform while it is considered down.
+var ErrCircuitOpen = errors.New("platform circuit open")
+
+const (
+	// breakerThreshold is the number of consecutive failures opening the circuit.
+	breakerThreshold = 5
+	// breakerCooldown is the time the circuit stays open before a trial request is let through.
+	breakerCooldown = 30 * time.Second
+	// maxRetryAfter caps the delay requested by a Retry-After header.
+	maxRetryAfter = 5 * time.Minute
+)
+
+// RetryPolicy configures the retries of failed platform calls.
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry, doubled with every further retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts.
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used by clients created with NewClient.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 4,
+	BaseDelay:   500 * time.Millisecond,
+	MaxDelay:    30 * time.Second,
+}
+
+// backoff returns the delay before retry number n, starting with 1, using full jitter.
+func (p RetryPolicy) backoff(n int) time.Duration {
+	d := p.BaseDelay << (n - 1)
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	if d <= 0 {
+		return 0
+	}
+	return rand.N(d)
+}
+
+// SetRetryPolicy replaces the retry policy of the client.
+func (c *Client) SetRetryPolicy(p RetryPolicy) {
+	c.retry = p
+}
+
+// do sends req, retrying transport errors and temporary platform errors with
+// exponential backoff. Calls which aren't idempotent are only retried if the
+// platform rejected them with 429 Too Many Requests. The latency of each
+// attempt is observed for endpoint.
+func (c *Client) do(req *http.Request, endpoint string, idempotent bool) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 1; ; attempt++ {
+		// the body is rewound before the breaker may let a trial request
+		// through, which has to be recorded once allowed
+		if attempt > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, fmt.Errorf("failed to rewind request body: %w", err)
+			}
+			req.Body = body
+		}
+		if err := c.breaker.allow(); err != nil {
+			return nil, err
+		}
+
+		start := time.Now()
+		res, err := c.http.Do(req)
+		status := 0
+		if err == nil {
+			status = res.StatusCode
+		}
+		metrics.PlatformRequest(endpoint, req.Method, status, time.Since(start))
+		if ctx.Err() != nil {
+			// the caller gave up, e.g. on shutdown or an exceeded transfer
+			// timeout, which says nothing about the platform
+			c.breaker.release()
+		} else {
+			c.breaker.record(err == nil && res.StatusCode < http.StatusInternalServerError)
+		}
+
+		retry, delay := c.shouldRetry(res, err, idempotent, attempt)
+		replayable := req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+		if !retry || !replayable || attempt >= c.retry.MaxAttempts || ctx.Err() != nil {
+			return res, err
+		}
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return res, err
+		}
+		if res != nil {
+			res.Body.Close()
+		}
+
+		c.logger.Warn("retrying platform request", "method", req.Method, "url", req.URL.Redacted(), "attempt", attempt, "delay", delay, "error", describe(res, err))
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// shouldRetry reports whether the attempt with result res and err is retried and the delay before the retry.
+func (c *Client) shouldRetry(res *http.Response, err error, idempotent bool, attempt int) (bool, time.Duration) {
+	delay := c.retry.backoff(attempt)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return false, 0
+		}
+		return idempotent, delay
+	}
+
+	switch res.StatusCode {
+	case http.StatusTooManyRequests, http.StatusServiceUnavailable:
+		if d, ok := retryAfter(res.Header.Get("Retry-After")); ok {
+			delay = d
+		}
+		return idempotent || res.StatusCode == http.StatusTooManyRequests, delay
+	case http.StatusBadGateway, http.StatusGatewayTimeout:
+		return idempotent, delay
+	}
+	return false, 0
+}
+
+// retryAfter parses the value of a Retry-After header given in seconds or as http date.
+func retryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	var d time.Duration
+	if seconds, err := strconv.Atoi(value); err == nil {
+		d = time.Duration(seconds) * time.Second
+	} else if t, err := http.ParseTime(value); err == nil {
+		d = time.Until(t)
+	} else {
+		return 0, false
+	}
+	return min(max(d, 0), maxRetryAfter), true
+}
+
+func describe(res *http.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	return res.Status
+}
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker stops calls to a platform after repeated failures. It is shared by
+// all clients using the same platform url.
+type breaker struct {
+	logger *slog.Logger
+	url    string
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int
+	openedAt time.Time
+}
+
+var (
+	breakersMu sync.Mutex
+	breakers   = make(map[string]*breaker)
+)
+
+// breakerFor returns the circuit breaker of the platform at url.
+func breakerFor(logger *slog.Logger, url string) *breaker {
+	breakersMu.Lock()
+	defer breakersMu.Unlock()
+	b, ok := breakers[url]
+	if !ok {
+		b = &breaker{logger: logger, url: url}
+		breakers[url] = b
+	}
+	return b
+}
+
+// allow returns ErrCircuitOpen if calls are currently stopped. Once the
+// cooldown passed a single trial call is allowed.
+func (b *breaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case breakerOpen:
+		if time.Since(b.openedAt) < breakerCooldown {
+			return fmt.Errorf("%w: retrying after %s", ErrCircuitOpen, b.openedAt.Add(breakerCooldown).Format(time.RFC3339))
+		}
+		b.state = breakerHalfOpen
+		b.logger.Info("platform circuit half-open, sending trial request", "url", b.url)
+	case breakerHalfOpen:
+		return fmt.Errorf("%w: trial request pending", ErrCircuitOpen)
+	}
+	return nil
+}
+
+// record updates the breaker with the outcome of a call.
+func (b *breaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		if b.state != breakerClosed {
+			b.logger.Info("platform circuit closed", "url", b.url)
+		}
+		b.state = breakerClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || (b.state == breakerClosed && b.failures >= breakerThreshold) {
+		b.logger.Warn("platform circuit opened", "url", b.url, "failures", b.failures, "cooldown", breakerCooldown)
+		b.state = breakerOpen
+		b.openedAt = time.Now()
+	}
+}
+
+// release ends a call without outcome. A trial call is allowed again.
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == breakerHalfOpen {
+		b.state = breakerOpen
+		b.openedAt = time.Now().Add(-breakerCooldown)
+	}
+}
+
+// Unavailable reports whether calls to the platform are stopped after
+// repeated failures and the time the next trial call is allowed.
+func (c *Client) Unavailable() (time.Time, bool) {
+	b := c.breaker
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state != breakerOpen {
+		return time.Time{}, false
+	}
+	until := b.openedAt.Add(breakerCooldown)
+	return until, time.Now().Before(until)
+}
@@ -0,0 +1,150 @@
+package platform_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+	"github.com/myopenfactory/edi-connector/v2/platform"
+)
+
+func newTestClient(t *testing.T, url string, policy platform.RetryPolicy) *platform.Client {
+	t.Helper()
+	os.Setenv("EDI_CONNECTOR", fmt.Sprintf("%s:%s", testUsername, testPassword))
+	t.Cleanup(func() { os.Unsetenv("EDI_CONNECTOR") })
+	cl, err := platform.NewClient(slog.New(slog.NewTextHandler(io.Discard, nil)), url, "", credentials.NewEnvCredManager(), "")
+	if err != nil {
+		t.Fatalf("failed to create edi client: %v", err)
+	}
+	cl.SetRetryPolicy(policy)
+	return cl
+}
+
+func TestRetryIdempotentCall(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte(`{"transmissions": []}`))
+	}))
+	defer server.Close()
+
+	cl := newTestClient(t, server.URL, platform.RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+	if _, err := cl.ListTransmissions(t.Context(), "1", ""); err != nil {
+		t.Errorf("failed to list transmissions: %v", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("Expected calls: %d, got: %d", 3, calls.Load())
+	}
+}
+
+func TestNoRetryOfUpload(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	cl := newTestClient(t, server.URL, platform.RetryPolicy{MaxAttempts: 4, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+	if err := cl.AddTransmission(t.Context(), "1", "", []byte("data")); err == nil {
+		t.Error("Expected error for bad gateway")
+	}
+	if calls.Load() != 1 {
+		t.Errorf("Expected calls: %d, got: %d", 1, calls.Load())
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "data" {
+			t.Errorf("Expected request data: %s, got: %s", "data", body)
+		}
+	}))
+	defer server.Close()
+
+	cl := newTestClient(t, server.URL, platform.RetryPolicy{MaxAttempts: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})
+	start := time.Now()
+	if err := cl.AddTransmission(t.Context(), "1", "", []byte("data")); err != nil {
+		t.Errorf("failed to add transmission: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected retry after at least %v, got: %v", time.Second, elapsed)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("Expected calls: %d, got: %d", 2, calls.Load())
+	}
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	cl := newTestClient(t, server.URL, platform.RetryPolicy{MaxAttempts: 1})
+	for range 5 {
+		if _, err := cl.ListTransmissions(t.Context(), "1", ""); err == nil {
+			t.Fatal("Expected error for internal server error")
+		}
+	}
+	if _, unavailable := cl.Unavailable(); !unavailable {
+		t.Error("Expected platform to be unavailable")
+	}
+
+	_, err := cl.ListTransmissions(t.Context(), "1", "")
+	if !errors.Is(err, platform.ErrCircuitOpen) {
+		t.Errorf("Expected error: %v, got: %v", platform.ErrCircuitOpen, err)
+	}
+	if calls.Load() != 5 {
+		t.Errorf("Expected calls: %d, got: %d", 5, calls.Load())
+	}
+}
+
+func TestCanceledCallsKeepCircuitClosed(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	cl := newTestClient(t, server.URL, platform.RetryPolicy{MaxAttempts: 1})
+	for range 10 {
+		ctx, cancel := context.WithTimeout(t.Context(), 10*time.Millisecond)
+		_, err := cl.ListTransmissions(ctx, "1", "")
+		cancel()
+		if err == nil {
+			t.Fatal("Expected error for exceeded deadline")
+		}
+		if errors.Is(err, platform.ErrCircuitOpen) {
+			t.Fatalf("Expected circuit to stay closed, got: %v", err)
+		}
+	}
+	if _, unavailable := cl.Unavailable(); unavailable {
+		t.Error("Expected platform to be available")
+	}
+}
@@ -0,0 +1,173 @@
+// Package schedule determines when a process of the connector is run.
+//
+// A schedule runs a process either on a fixed interval or on a cron
+// expression and skips recurring blackout windows, both evaluated in the
+// configured time zone.
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/robfig/cron/v3"
+)
+
+// maxSkips limits the number of runs skipped due to blackouts while
+// searching the next run, protecting against windows covering every run.
+const maxSkips = 1000
+
+var weekdays = map[string]time.Weekday{
+	"SUN": time.Sunday,
+	"MON": time.Monday,
+	"TUE": time.Tuesday,
+	"WED": time.Wednesday,
+	"THU": time.Thursday,
+	"FRI": time.Friday,
+	"SAT": time.Saturday,
+}
+
+type blackout struct {
+	// days the window starts on, empty for every day
+	days     map[time.Weekday]bool
+	from, to time.Duration
+}
+
+// Schedule calculates the runs of a process.
+type Schedule struct {
+	interval  time.Duration
+	cron      cron.Schedule
+	location  *time.Location
+	blackouts []blackout
+}
+
+// New creates a schedule running every interval, or on the cron expression of cfg if set.
+func New(interval time.Duration, cfg config.ScheduleConfig) (*Schedule, error) {
+	s := &Schedule{
+		interval: interval,
+		location: time.Local,
+	}
+	if cfg.TimeZone != "" {
+		location, err := time.LoadLocation(cfg.TimeZone)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load time zone %q: %w", cfg.TimeZone, err)
+		}
+		s.location = location
+	}
+	if cfg.Cron != "" {
+		schedule, err := cron.ParseStandard(cfg.Cron)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse cron expression %q: %w", cfg.Cron, err)
+		}
+		s.cron = schedule
+	} else if interval <= 0 {
+		return nil, fmt.Errorf("interval must be positive, got: %v", interval)
+	}
+
+	for _, bc := range cfg.Blackouts {
+		b, err := parseBlackout(bc)
+		if err != nil {
+			return nil, err
+		}
+		s.blackouts = append(s.blackouts, b)
+	}
+	return s, nil
+}
+
+func parseBlackout(cfg config.BlackoutConfig) (blackout, error) {
+	var b blackout
+	var err error
+	if b.from, err = parseTimeOfDay(cfg.From); err != nil {
+		return blackout{}, fmt.Errorf("invalid blackout start: %w", err)
+	}
+	if b.to, err = parseTimeOfDay(cfg.To); err != nil {
+		return blackout{}, fmt.Errorf("invalid blackout end: %w", err)
+	}
+	if b.from == b.to {
+		return blackout{}, fmt.Errorf("blackout from %s to %s is empty", cfg.From, cfg.To)
+	}
+	if len(cfg.Days) > 0 {
+		b.days = make(map[time.Weekday]bool)
+	}
+	for _, day := range cfg.Days {
+		weekday, ok := weekdays[strings.ToUpper(day)]
+		if !ok && len(day) > 3 {
+			weekday, ok = weekdays[strings.ToUpper(day[:3])]
+		}
+		if !ok {
+			return blackout{}, fmt.Errorf("invalid blackout day %q", day)
+		}
+		b.days[weekday] = true
+	}
+	return b, nil
+}
+
+// parseTimeOfDay parses a time formatted as 15:04 into the duration since midnight.
+func parseTimeOfDay(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("time %q isn't formatted as hh:mm", value)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// Next returns the first run after t which isn't within a blackout. The zero
+// time is returned if there is no such run.
+func (s *Schedule) Next(t time.Time) time.Time {
+	next := s.next(t)
+	for range maxSkips {
+		if next.IsZero() {
+			return next
+		}
+		end, blocked := s.Blocked(next)
+		if !blocked {
+			return next
+		}
+		if s.cron == nil {
+			// the window end may be within an adjacent window
+			next = end
+			continue
+		}
+		// the cron schedule works on full seconds, so the window end itself is a candidate
+		next = s.next(end.Add(-time.Nanosecond))
+	}
+	return time.Time{}
+}
+
+func (s *Schedule) next(t time.Time) time.Time {
+	if s.cron != nil {
+		return s.cron.Next(t.In(s.location))
+	}
+	return t.Add(s.interval)
+}
+
+// Blocked reports whether t is within a blackout window and returns the end of the window.
+func (s *Schedule) Blocked(t time.Time) (time.Time, bool) {
+	t = t.In(s.location)
+	midnight := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, s.location)
+	var end time.Time
+	for _, b := range s.blackouts {
+		// windows ending after midnight may have started the day before
+		for _, day := range []time.Time{midnight.AddDate(0, 0, -1), midnight} {
+			if b.days != nil && !b.days[day.Weekday()] {
+				continue
+			}
+			start := s.timeOfDay(day, b.from)
+			stop := s.timeOfDay(day, b.to)
+			if b.to < b.from {
+				stop = s.timeOfDay(day.AddDate(0, 0, 1), b.to)
+			}
+			if !t.Before(start) && t.Before(stop) && stop.After(end) {
+				end = stop
+			}
+		}
+	}
+	return end, !end.IsZero()
+}
+
+// timeOfDay returns the wall clock time d after midnight on day. Unlike adding
+// d to midnight, it stays on the wall clock on days changing daylight saving time.
+func (s *Schedule) timeOfDay(day time.Time, d time.Duration) time.Time {
+	return time.Date(day.Year(), day.Month(), day.Day(), int(d/time.Hour), int(d%time.Hour/time.Minute), 0, 0, s.location)
+}
@@ -0,0 +1,155 @@
+package schedule_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/schedule"
+)
+
+func TestInterval(t *testing.T) {
+	s, err := schedule.New(5*time.Minute, config.ScheduleConfig{})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	expected := now.Add(5 * time.Minute)
+	if next := s.Next(now); !next.Equal(expected) {
+		t.Errorf("Expected next run: %v, got: %v", expected, next)
+	}
+}
+
+func TestCronWithTimeZone(t *testing.T) {
+	s, err := schedule.New(time.Minute, config.ScheduleConfig{
+		Cron:     "0 1 * * *",
+		TimeZone: "Europe/Berlin",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+	expected := time.Date(2024, 1, 16, 0, 0, 0, 0, time.UTC)
+	if next := s.Next(now); !next.Equal(expected) {
+		t.Errorf("Expected next run: %v, got: %v", expected, next)
+	}
+}
+
+func TestBlackout(t *testing.T) {
+	s, err := schedule.New(30*time.Minute, config.ScheduleConfig{
+		TimeZone: "UTC",
+		Blackouts: []config.BlackoutConfig{
+			{Days: []string{"SUN"}, From: "02:00", To: "04:00"},
+			{From: "23:00", To: "01:00"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+
+	// 2024-03-03 is a sunday
+	now := time.Date(2024, 3, 3, 1, 45, 0, 0, time.UTC)
+	expected := time.Date(2024, 3, 3, 4, 0, 0, 0, time.UTC)
+	if next := s.Next(now); !next.Equal(expected) {
+		t.Errorf("Expected next run: %v, got: %v", expected, next)
+	}
+
+	now = time.Date(2024, 3, 4, 1, 45, 0, 0, time.UTC)
+	expected = time.Date(2024, 3, 4, 2, 15, 0, 0, time.UTC)
+	if next := s.Next(now); !next.Equal(expected) {
+		t.Errorf("Expected next run on monday: %v, got: %v", expected, next)
+	}
+
+	if _, blocked := s.Blocked(time.Date(2024, 3, 5, 0, 30, 0, 0, time.UTC)); !blocked {
+		t.Error("Expected blackout spanning midnight to block")
+	}
+}
+
+func TestAdjacentBlackouts(t *testing.T) {
+	s, err := schedule.New(time.Hour, config.ScheduleConfig{
+		TimeZone: "UTC",
+		Blackouts: []config.BlackoutConfig{
+			{From: "22:00", To: "00:00"},
+			{From: "00:00", To: "06:00"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+	now := time.Date(2024, 3, 4, 21, 30, 0, 0, time.UTC)
+	expected := time.Date(2024, 3, 5, 6, 0, 0, 0, time.UTC)
+	next := s.Next(now)
+	if !next.Equal(expected) {
+		t.Errorf("Expected next run after both windows: %v, got: %v", expected, next)
+	}
+	if _, blocked := s.Blocked(next); blocked {
+		t.Errorf("Expected next run %v to not be blocked", next)
+	}
+}
+
+func TestBlackoutDaylightSaving(t *testing.T) {
+	s, err := schedule.New(30*time.Minute, config.ScheduleConfig{
+		TimeZone: "Europe/Berlin",
+		Blackouts: []config.BlackoutConfig{
+			{Days: []string{"SUN"}, From: "02:00", To: "04:00"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		t       time.Time
+		blocked bool
+		end     time.Time
+	}{
+		// 2024-03-31 clocks move from 02:00 CET to 03:00 CEST
+		{name: "spring within", t: time.Date(2024, 3, 31, 1, 30, 0, 0, time.UTC), blocked: true, end: time.Date(2024, 3, 31, 2, 0, 0, 0, time.UTC)},
+		{name: "spring after", t: time.Date(2024, 3, 31, 2, 30, 0, 0, time.UTC)},
+		// 2024-10-27 clocks move from 03:00 CEST to 02:00 CET
+		{name: "autumn before", t: time.Date(2024, 10, 26, 23, 30, 0, 0, time.UTC)},
+		{name: "autumn within", t: time.Date(2024, 10, 27, 2, 30, 0, 0, time.UTC), blocked: true, end: time.Date(2024, 10, 27, 3, 0, 0, 0, time.UTC)},
+	}
+	for _, test := range tests {
+		end, blocked := s.Blocked(test.t)
+		if blocked != test.blocked {
+			t.Errorf("%s: expected blocked: %t, got: %t", test.name, test.blocked, blocked)
+		}
+		if blocked && !end.Equal(test.end) {
+			t.Errorf("%s: expected end: %v, got: %v", test.name, test.end, end)
+		}
+	}
+}
+
+func TestCronBlackout(t *testing.T) {
+	s, err := schedule.New(time.Minute, config.ScheduleConfig{
+		Cron:     "0 * * * *",
+		TimeZone: "UTC",
+		Blackouts: []config.BlackoutConfig{
+			{Days: []string{"sunday"}, From: "02:00", To: "04:00"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to create schedule: %v", err)
+	}
+	now := time.Date(2024, 3, 3, 1, 30, 0, 0, time.UTC)
+	expected := time.Date(2024, 3, 3, 4, 0, 0, 0, time.UTC)
+	if next := s.Next(now); !next.Equal(expected) {
+		t.Errorf("Expected next run: %v, got: %v", expected, next)
+	}
+}
+
+func TestInvalidSchedule(t *testing.T) {
+	configs := []config.ScheduleConfig{
+		{Cron: "not a cron"},
+		{TimeZone: "Mars/Olympus"},
+		{Blackouts: []config.BlackoutConfig{{From: "25:00", To: "04:00"}}},
+		{Blackouts: []config.BlackoutConfig{{Days: []string{"someday"}, From: "02:00", To: "04:00"}}},
+	}
+	for _, cfg := range configs {
+		if _, err := schedule.New(time.Minute, cfg); err == nil {
+			t.Errorf("Expected error for schedule %+v", cfg)
+		}
+	}
+}
@@ -0,0 +1,298 @@
+// Package state persists information about transfers across restarts of the connector.
+//
+// Each transfer is journaled with the phase it reached, so an interrupted
+// transfer can be resumed without delivering or uploading it twice.
+package state
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const stateFile = "state.json"
+
+// Phase is the processing step a transfer reached.
+type Phase string
+
+const (
+	// inbound transmissions
+	PhaseListed     Phase = "listed"
+	PhaseDownloaded Phase = "downloaded"
+	PhaseDelivered  Phase = "delivered"
+	// PhaseRejected marks a transmission to be confirmed as failed with the
+	// last error of its record.
+	PhaseRejected  Phase = "rejected"
+	PhaseConfirmed Phase = "confirmed"
+
+	// outbound messages and attachments
+	PhaseRead      Phase = "read"
+	PhaseUploaded  Phase = "uploaded"
+	PhaseFinalized Phase = "finalized"
+)
+
+// Completed reports whether no further work is required for a transfer in phase p.
+func (p Phase) Completed() bool {
+	return p == PhaseConfirmed || p == PhaseFinalized
+}
+
+const (
+	DirectionInbound  = "inbound"
+	DirectionOutbound = "outbound"
+)
+
+// Record tracks the processing of a single transfer.
+type Record struct {
+	Direction string            `json:"direction,omitempty"`
+	ConfigId  string            `json:"configId,omitempty"`
+	AuthName  string            `json:"authName,omitempty"`
+	ObjectId  string            `json:"objectId,omitempty"`
+	Hash      string            `json:"hash,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Phase     Phase             `json:"phase,omitempty"`
+	// Status is the status message reported by the inbound transport after delivery.
+	Status string `json:"status,omitempty"`
+	// Attachments lists the urls of attachments already delivered for an inbound transmission.
+	Attachments []string  `json:"attachments,omitempty"`
+	Attempts    int       `json:"attempts"`
+	LastError   string    `json:"lastError,omitempty"`
+	Updated     time.Time `json:"updated"`
+}
+
+// Store is a file backed key value store for transfer records. Every change
+// is written to disk before it is reported as successful.
+type Store struct {
+	mu      sync.Mutex
+	path    string
+	records map[string]Record
+	// hashes indexes the keys of records by their content hash
+	hashes map[string]map[string]struct{}
+}
+
+// Open loads the store located within dir. The directory is created if it
+// doesn't exist. If dir is empty the store is kept in memory only.
+func Open(dir string) (*Store, error) {
+	s := &Store{
+		records: make(map[string]Record),
+		hashes:  make(map[string]map[string]struct{}),
+	}
+	if dir == "" {
+		return s, nil
+	}
+
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create data directory: %w", err)
+	}
+	s.path = filepath.Join(dir, stateFile)
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.records); err != nil {
+		return nil, fmt.Errorf("failed to decode state file %s: %w", s.path, err)
+	}
+	for key, record := range s.records {
+		s.index(key, record)
+	}
+	return s, nil
+}
+
+// InboundKey returns the key of an inbound transmission for configId.
+func InboundKey(configId, transmissionId string) string {
+	return "inbound/" + configId + "/" + transmissionId
+}
+
+// OutboundKey returns the key of an outbound object for configId. The hash of
+// the content is part of the key, so a new file reusing a name is a new transfer.
+func OutboundKey(configId, objectId, hash string) string {
+	return "outbound/" + configId + "/" + objectId + "/" + hash
+}
+
+// Get returns the record stored for key.
+func (s *Store) Get(key string) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	record, ok := s.records[key]
+	return record, ok
+}
+
+// Update applies fn to the record stored for key, or to an empty record if
+// there is none, and persists the result.
+func (s *Store) Update(key string, fn func(*Record)) (Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.update(key, fn)
+}
+
+// UpdateUnique is Update unless a record with the content hash matches
+// duplicate, which is returned instead without changing the store. As the
+// lookup and the update happen at once, only one of several transfers with
+// the same content passes.
+func (s *Store) UpdateUnique(key, hash string, duplicate func(key string, r Record) bool, fn func(*Record)) (Record, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if record, ok := s.findHash(hash, duplicate); ok {
+		return record, true, nil
+	}
+	record, err := s.update(key, fn)
+	return record, false, err
+}
+
+func (s *Store) update(key string, fn func(*Record)) (Record, error) {
+	record := s.records[key]
+	fn(&record)
+	record.Updated = time.Now()
+
+	previous, existed := s.records[key]
+	s.put(key, record)
+	if err := s.save(); err != nil {
+		if existed {
+			s.put(key, previous)
+		} else {
+			s.remove(key)
+		}
+		return Record{}, err
+	}
+	return record, nil
+}
+
+// Delete removes the record stored for key.
+func (s *Store) Delete(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	previous, ok := s.records[key]
+	if !ok {
+		return nil
+	}
+	s.remove(key)
+	if err := s.save(); err != nil {
+		s.put(key, previous)
+		return err
+	}
+	return nil
+}
+
+// FindHash returns a record with the content hash matching fn.
+func (s *Store) FindHash(hash string, fn func(key string, r Record) bool) (Record, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.findHash(hash, fn)
+}
+
+func (s *Store) findHash(hash string, fn func(key string, r Record) bool) (Record, bool) {
+	for key := range s.hashes[hash] {
+		if record := s.records[key]; fn(key, record) {
+			return record, true
+		}
+	}
+	return Record{}, false
+}
+
+// put stores record for key and updates the hash index.
+func (s *Store) put(key string, record Record) {
+	s.remove(key)
+	s.records[key] = record
+	s.index(key, record)
+}
+
+// remove deletes the record of key and its entry within the hash index.
+func (s *Store) remove(key string) {
+	record, ok := s.records[key]
+	if !ok {
+		return
+	}
+	delete(s.records, key)
+	if keys := s.hashes[record.Hash]; keys != nil {
+		delete(keys, key)
+		if len(keys) == 0 {
+			delete(s.hashes, record.Hash)
+		}
+	}
+}
+
+func (s *Store) index(key string, record Record) {
+	if record.Hash == "" {
+		return
+	}
+	keys := s.hashes[record.Hash]
+	if keys == nil {
+		keys = make(map[string]struct{})
+		s.hashes[record.Hash] = keys
+	}
+	keys[key] = struct{}{}
+}
+
+// Pending returns all records of transfers which are not completed yet.
+func (s *Store) Pending() map[string]Record {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pending := make(map[string]Record)
+	for key, record := range s.records {
+		if !record.Phase.Completed() {
+			pending[key] = record
+		}
+	}
+	return pending
+}
+
+// Prune removes completed records last updated before t.
+func (s *Store) Prune(t time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	removed := make(map[string]Record)
+	for key, record := range s.records {
+		if record.Phase.Completed() && record.Updated.Before(t) {
+			removed[key] = record
+			s.remove(key)
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+	if err := s.save(); err != nil {
+		for key, record := range removed {
+			s.put(key, record)
+		}
+		return err
+	}
+	return nil
+}
+
+// save writes all records into a temporary file which replaces the state file,
+// so a crash never leaves a partially written state behind.
+func (s *Store) save() error {
+	if s.path == "" {
+		return nil
+	}
+	data, err := json.Marshal(s.records)
+	if err != nil {
+		return fmt.Errorf("failed to encode state: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), stateFile+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary state file: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write state: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync state: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close state file: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace state file: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,142 @@
+package state_test
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/state"
+)
+
+func TestPersistence(t *testing.T) {
+	dir := t.TempDir()
+	store, err := state.Open(dir)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+
+	key := state.InboundKey("4711", "1")
+	for range 2 {
+		if _, err := store.Update(key, func(r *state.Record) {
+			r.Attempts++
+			r.LastError = "failed"
+		}); err != nil {
+			t.Fatalf("Failed to update record: %v", err)
+		}
+	}
+
+	store, err = state.Open(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	record, ok := store.Get(key)
+	if !ok {
+		t.Fatal("Expected record to be persisted")
+	}
+	if record.Attempts != 2 {
+		t.Errorf("Expected attempts: %d, got: %d", 2, record.Attempts)
+	}
+	if record.LastError != "failed" {
+		t.Errorf("Expected last error: %s, got: %s", "failed", record.LastError)
+	}
+
+	if err := store.Delete(key); err != nil {
+		t.Fatalf("Failed to delete record: %v", err)
+	}
+	store, err = state.Open(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	if _, ok := store.Get(key); ok {
+		t.Error("Expected record to be deleted")
+	}
+}
+
+func TestMemoryStore(t *testing.T) {
+	store, err := state.Open("")
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	record, err := store.Update("key", func(r *state.Record) { r.Attempts++ })
+	if err != nil {
+		t.Fatalf("Failed to update record: %v", err)
+	}
+	if record.Attempts != 1 {
+		t.Errorf("Expected attempts: %d, got: %d", 1, record.Attempts)
+	}
+}
+
+func TestPendingAndPrune(t *testing.T) {
+	store, err := state.Open(t.TempDir())
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	uploaded := state.OutboundKey("4711", "a.xml", "abc")
+	finalized := state.OutboundKey("4711", "b.xml", "def")
+	for key, phase := range map[string]state.Phase{uploaded: state.PhaseUploaded, finalized: state.PhaseFinalized} {
+		if _, err := store.Update(key, func(r *state.Record) { r.Phase = phase }); err != nil {
+			t.Fatalf("Failed to update record: %v", err)
+		}
+	}
+
+	pending := store.Pending()
+	if _, ok := pending[uploaded]; !ok || len(pending) != 1 {
+		t.Errorf("Expected pending: [%s], got: %v", uploaded, pending)
+	}
+
+	if err := store.Prune(time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("Failed to prune store: %v", err)
+	}
+	if _, ok := store.Get(finalized); ok {
+		t.Error("Expected completed record to be pruned")
+	}
+	if _, ok := store.Get(uploaded); !ok {
+		t.Error("Expected pending record to be kept")
+	}
+}
+
+func TestUpdateUnique(t *testing.T) {
+	dir := t.TempDir()
+	store, err := state.Open(dir)
+	if err != nil {
+		t.Fatalf("Failed to open store: %v", err)
+	}
+	other := func(key string) func(string, state.Record) bool {
+		return func(k string, r state.Record) bool { return k != key }
+	}
+
+	var passed atomic.Int32
+	var wg sync.WaitGroup
+	for _, name := range []string{"a.xml", "b.xml", "c.xml"} {
+		wg.Go(func() {
+			key := state.OutboundKey("4711", name, "abc")
+			_, duplicate, err := store.UpdateUnique(key, "abc", other(key), func(r *state.Record) {
+				r.ObjectId = name
+				r.Hash = "abc"
+			})
+			if err != nil {
+				t.Errorf("Failed to update record: %v", err)
+			}
+			if !duplicate {
+				passed.Add(1)
+			}
+		})
+	}
+	wg.Wait()
+	if passed.Load() != 1 {
+		t.Errorf("Expected a single record to pass, got: %d", passed.Load())
+	}
+
+	store, err = state.Open(dir)
+	if err != nil {
+		t.Fatalf("Failed to reopen store: %v", err)
+	}
+	key := state.OutboundKey("4711", "d.xml", "abc")
+	if _, ok := store.FindHash("abc", other(key)); !ok {
+		t.Error("Expected record to be found by hash after reopening")
+	}
+	if _, ok := store.FindHash("def", other(key)); ok {
+		t.Error("Expected no record for unknown hash")
+	}
+}
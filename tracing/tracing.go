@@ -0,0 +1,92 @@
+// Package tracing exports traces of transfers to an OTLP collector.
+//
+// Every outbound file and every inbound transmission is recorded as its own
+// trace. Without a configured collector spans are discarded.
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/version"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/myopenfactory/edi-connector/v2"
+
+// Attributes of transfer spans.
+const (
+	ConfigId       = attribute.Key("edi.config_id")
+	AuthName       = attribute.Key("edi.auth_name")
+	TransmissionId = attribute.Key("edi.transmission_id")
+	Filename       = attribute.Key("edi.filename")
+)
+
+// Setup exports spans to the collector configured in cfg and propagates the
+// trace context in the W3C trace context headers. The returned function
+// flushes pending spans and stops the export.
+func Setup(ctx context.Context, cfg config.TracingConfig) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	if cfg.Endpoint == "" {
+		return func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace exporter: %w", err)
+	}
+	ratio := 1.0
+	if cfg.SampleRatio != nil {
+		ratio = *cfg.SampleRatio
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+		sdktrace.WithResource(resource.NewWithAttributes(semconv.SchemaURL,
+			semconv.ServiceName("edi-connector"),
+			semconv.ServiceVersion(version.Version),
+		)),
+	)
+	otel.SetTracerProvider(provider)
+	return provider.Shutdown, nil
+}
+
+// Start starts a span with name as child of the span in ctx.
+func Start(ctx context.Context, name string, opts ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, opts...)
+}
+
+// StartRoot starts a span with name as root of a new trace. The span is
+// linked to origin, e.g. the listing which found the transferred object.
+func StartRoot(ctx context.Context, name string, origin trace.SpanContext, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Start(ctx, name,
+		trace.WithNewRoot(),
+		trace.WithLinks(trace.Link{SpanContext: origin}),
+		trace.WithAttributes(attrs...),
+	)
+}
+
+// Fail marks the span in ctx as failed with err without ending it.
+func Fail(ctx context.Context, err error) {
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// End ends span and marks it as failed if err isn't nil.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
@@ -0,0 +1,55 @@
+package tracing_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/trace/noop"
+)
+
+func TestSetupExportsSpans(t *testing.T) {
+	var exported atomic.Int32
+	collector := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/traces" {
+			t.Errorf("Expected export to /v1/traces, got: %s", r.URL.Path)
+		}
+		exported.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer collector.Close()
+	t.Cleanup(func() { otel.SetTracerProvider(noop.NewTracerProvider()) })
+
+	shutdown, err := tracing.Setup(context.TODO(), config.TracingConfig{Endpoint: collector.URL})
+	if err != nil {
+		t.Fatalf("Failed to setup tracing: %v", err)
+	}
+
+	ctx, root := tracing.Start(context.TODO(), "outbound message")
+	_, child := tracing.Start(ctx, "AddTransmission")
+	tracing.End(child, errors.New("upload failed"))
+	tracing.End(root, nil)
+
+	if err := shutdown(context.TODO()); err != nil {
+		t.Fatalf("Failed to shutdown tracing: %v", err)
+	}
+	if exported.Load() == 0 {
+		t.Errorf("Expected spans to be exported")
+	}
+}
+
+func TestSetupWithoutEndpoint(t *testing.T) {
+	shutdown, err := tracing.Setup(context.TODO(), config.TracingConfig{})
+	if err != nil {
+		t.Fatalf("Failed to setup tracing: %v", err)
+	}
+	if err := shutdown(context.TODO()); err != nil {
+		t.Errorf("Expected no error on shutdown, got: %v", err)
+	}
+}
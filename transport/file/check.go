@@ -0,0 +1,40 @@
+package file
+
+import (
+	"fmt"
+	"os"
+)
+
+// checkFolders verifies all non-empty paths are readable folders.
+func checkFolders(paths ...string) error {
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return fmt.Errorf("folder %s is not accessible: %w", path, err)
+		}
+		info, err := f.Stat()
+		f.Close()
+		if err != nil {
+			return fmt.Errorf("folder %s is not accessible: %w", path, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("%s is not a folder", path)
+		}
+	}
+	return nil
+}
+
+// checkWritableFolder verifies path is a folder files can be created in and
+// removed from. The folder is only inspected, nothing is written to it.
+func checkWritableFolder(path string) error {
+	if err := checkFolders(path); err != nil {
+		return err
+	}
+	if err := checkWritable(path); err != nil {
+		return fmt.Errorf("folder %s is not writable: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package file
+
+// checkWritable is a no-op, the permissions of a folder aren't determined
+// without writing to it on this platform.
+func checkWritable(path string) error {
+	return nil
+}
@@ -0,0 +1,11 @@
+//go:build unix
+
+package file
+
+import "golang.org/x/sys/unix"
+
+// checkWritable verifies the permissions of the folder at path allow the
+// process to create and remove files.
+func checkWritable(path string) error {
+	return unix.Access(path, unix.W_OK|unix.X_OK)
+}
@@ -3,6 +3,7 @@ package file
 import (
 	"context"
 	"fmt"
+	"io"
 	"log/slog"
 	"os"
 	"path/filepath"
@@ -27,6 +28,11 @@ type inboundFileTransport struct {
 	settings inboundFileSettings
 }
 
+// Check verifies the configured folders are accessible.
+func (p *inboundFileTransport) Check(ctx context.Context) error {
+	return checkFolders(p.settings.Path, p.settings.AttachmentPath)
+}
+
 // NewInboundFileTransport returns new InTransport and checks for basefolder and exist parameter.
 func NewInboundTransport(logger *slog.Logger, configId, authName string, cfg map[string]any) (transport.InboundTransport, error) {
 	var settings inboundFileSettings
@@ -84,46 +90,87 @@ func (p *inboundFileTransport) HandleAttachment(url string) bool {
 // ConsumeMessage consumes message from plattform and saves it to a file
 func (p *inboundFileTransport) ProcessMessage(ctx context.Context, msg transport.Object) (string, error) {
 	if p.settings.Mode == "append" {
-		filename := msg.Id
-		if value, ok := msg.Metadata["filename"]; ok {
-			filename = value
-		}
-		path := filepath.Join(p.settings.Path, filename)
+		path := p.Target(msg, false)
 		p.logger.Info("Appending to file", "path", path)
 		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
 		if err != nil {
 			return "", fmt.Errorf("error while open file %s: %w", path, err)
 		}
 		defer f.Close()
-		_, err = f.Write(msg.Content)
+		content, _, err := msg.Reader()
 		if err != nil {
+			return "", fmt.Errorf("failed to read message content: %w", err)
+		}
+		defer content.Close()
+		if _, err := io.Copy(f, content); err != nil {
 			return "", fmt.Errorf("error while writing file %s: %w", path, err)
 		}
 		return fmt.Sprintf("Appending to file: %s", path), nil
 	}
 
-	return p.writeObject(msg, p.settings.Path)
+	return p.writeObject(msg, false)
 }
 
 // ProcessAttachment processes the attachment and writes it to specified path. In case of already existing file a
 // new filename is derived.
 func (p *inboundFileTransport) ProcessAttachment(ctx context.Context, atc transport.Object) error {
-	_, err := p.writeObject(atc, p.settings.AttachmentPath)
+	_, err := p.writeObject(atc, true)
 	return err
 }
 
-func (p *inboundFileTransport) writeObject(obj transport.Object, basePath string) (string, error) {
+// Target returns the path obj is written to, named after its filename
+// metadata or its id.
+func (p *inboundFileTransport) Target(obj transport.Object, attachment bool) string {
+	basePath := p.settings.Path
+	if attachment {
+		basePath = p.settings.AttachmentPath
+	}
 	filename := obj.Id
 	if value, ok := obj.Metadata["filename"]; ok && value != "" {
 		filename = value
 	}
-	path := filepath.Join(basePath, filename)
+	return filepath.Join(basePath, filename)
+}
+
+func (p *inboundFileTransport) writeObject(obj transport.Object, attachment bool) (string, error) {
+	path := p.Target(obj, attachment)
 
 	p.logger.Info("Creating file", "path", path)
-	err := os.WriteFile(path, obj.Content, 0644)
+	if obj.Path != "" {
+		// streamed content staged next to the destination only needs to be moved
+		if err := os.Chmod(obj.Path, 0644); err == nil && os.Rename(obj.Path, path) == nil {
+			return fmt.Sprintf("Created file: %s", path), nil
+		}
+	}
+
+	content, _, err := obj.Reader()
 	if err != nil {
+		return "", fmt.Errorf("failed to read content: %w", err)
+	}
+	defer content.Close()
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to write to file %q: %w", path, err)
+	}
+	if _, err := io.Copy(f, content); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to write to file %q: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
 		return "", fmt.Errorf("failed to write to file %q: %w", path, err)
 	}
 
 	return fmt.Sprintf("Created file: %s", path), nil
 }
+
+// StagingDir returns the folder messages or attachments are written to, so
+// streamed content is received next to its destination.
+func (p *inboundFileTransport) StagingDir(attachment bool) string {
+	if attachment {
+		return p.settings.AttachmentPath
+	}
+	if p.settings.Mode == "append" {
+		return ""
+	}
+	return p.settings.Path
+}
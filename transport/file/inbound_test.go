@@ -163,3 +163,53 @@ func TestProcessAttachment(t *testing.T) {
 		t.Errorf("Expected data: %s, got: %s", expectedData, data)
 	}
 }
+
+func TestProcessMessageStaged(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	inboundDir := t.TempDir()
+	inbound, err := file.NewInboundTransport(logger, "12345", "", map[string]any{
+		"path": inboundDir,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create inbound transport: %v", err)
+	}
+
+	stager, ok := inbound.(transport.Stager)
+	if !ok {
+		t.Fatal("Expected stager")
+	}
+	if dir := stager.StagingDir(false); dir != inboundDir {
+		t.Errorf("Expected staging dir: %s, got: %s", inboundDir, dir)
+	}
+	stagedPath := filepath.Join(inboundDir, ".staged.part")
+	if err := os.WriteFile(stagedPath, []byte("test"), 0600); err != nil {
+		t.Fatalf("Failed to create staged file: %v", err)
+	}
+
+	_, err = inbound.ProcessMessage(context.TODO(), transport.Object{
+		Id:   "78i7987129878921798",
+		Path: stagedPath,
+		Size: 4,
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(stagedPath)
+		},
+		Metadata: map[string]string{
+			"filename": "inbound.csv",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to process message: %v", err)
+	}
+
+	if _, err := os.Stat(stagedPath); !os.IsNotExist(err) {
+		t.Error("Expected staged file to be moved")
+	}
+	data, err := os.ReadFile(filepath.Join(inboundDir, "inbound.csv"))
+	if err != nil {
+		t.Fatalf("Could not read test file: %v", err)
+	}
+	expectedData := []byte("test")
+	if !bytes.Equal(data, expectedData) {
+		t.Errorf("Expected data: %s, got: %s", expectedData, data)
+	}
+}
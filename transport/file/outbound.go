@@ -2,12 +2,16 @@ package file
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"iter"
 	"log/slog"
+	"maps"
 	"os"
 	"path/filepath"
 	"slices"
+	"strings"
 	"time"
 
 	"github.com/myopenfactory/edi-connector/v2/config"
@@ -25,6 +29,14 @@ type outboundFileSettings struct {
 	Attachment  watchSetting `json:"attachment" yaml:"attachment"`
 	ErrorPath   string       `json:"errorPath" yaml:"errorPath"`
 	SuccessPath string       `json:"successPath" yaml:"successPath"`
+	// DuplicatePath receives messages already uploaded within the duplicate
+	// window of the connector, defaults to ErrorPath.
+	DuplicatePath string `json:"duplicatePath" yaml:"duplicatePath"`
+	// BatchSize limits the number of messages and attachments listed per run, 0 lists all files.
+	BatchSize int `json:"batchSize" yaml:"batchSize"`
+	// Watch picks up files as soon as they are written instead of waiting for
+	// the wait time to pass. Folders on network filesystems are polled.
+	Watch bool `json:"watch" yaml:"watch"`
 }
 
 type outboundFileTransport struct {
@@ -32,6 +44,7 @@ type outboundFileTransport struct {
 	configId string
 	authName string
 	settings outboundFileSettings
+	watcher  *watcher
 }
 
 func (p *outboundFileTransport) isMessageEnabled() bool {
@@ -71,7 +84,13 @@ func NewOutboundTransport(logger *slog.Logger, configId, authName string, cfg ma
 			return nil, fmt.Errorf("error folder does not exist: %v", settings.ErrorPath)
 		}
 
-		p.logger.Info("configured outbound process", "configId", p.configId, "authName", p.authName, "successFolder", settings.SuccessPath, "errorFolder", settings.ErrorPath)
+		if settings.DuplicatePath != "" {
+			if _, err := os.Stat(settings.DuplicatePath); os.IsNotExist(err) {
+				return nil, fmt.Errorf("duplicate folder does not exist: %v", settings.DuplicatePath)
+			}
+		}
+
+		p.logger.Info("configured outbound process", "configId", p.configId, "authName", p.authName, "successFolder", settings.SuccessPath, "errorFolder", settings.ErrorPath, "duplicateFolder", settings.DuplicatePath)
 
 		message := settings.Message
 		if _, err := os.Stat(message.Path); os.IsNotExist(err) {
@@ -94,6 +113,24 @@ func NewOutboundTransport(logger *slog.Logger, configId, authName string, cfg ma
 		p.logger.Info("attachment polling disabled")
 	}
 
+	if settings.Watch {
+		folders := make(map[string][]string)
+		if p.isMessageEnabled() {
+			folders[settings.Message.Path] = settings.Message.Extensions
+		}
+		if p.isAttachmentEnabled() {
+			folders[settings.Attachment.Path] = slices.Concat(folders[settings.Attachment.Path], settings.Attachment.Extensions)
+		}
+		paths := slices.Collect(maps.Keys(folders))
+		watcher, err := newWatcher(logger, folders)
+		if err != nil {
+			p.logger.Warn("filesystem notifications unavailable, falling back to polling", "configId", p.configId, "error", err)
+		} else {
+			p.watcher = watcher
+			p.logger.Info("watching folders for filesystem notifications", "configId", p.configId, "folders", paths)
+		}
+	}
+
 	return p, nil
 }
 
@@ -105,89 +142,116 @@ func (p *outboundFileTransport) AuthName() string {
 	return p.authName
 }
 
-// ListMessages lists all messages found within message folder. Each file gets
-// serialized into an transport.Object.
-func (p *outboundFileTransport) ListMessages(ctx context.Context) ([]transport.Object, error) {
-	messages := make([]transport.Object, 0)
-	if !p.isMessageEnabled() {
-		return messages, nil
-	}
-	message := p.settings.Message
-	duration, err := time.ParseDuration(message.WaitTime)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse duration: %w", err)
-	}
-	fileInfos, err := p.listFilesLastModifiedBefore(message.Path, time.Now().Add(-duration))
-	if err != nil {
-		return nil, fmt.Errorf("failed to list files within %s: %w", message.Path, err)
+// Check verifies the configured folders are accessible.
+func (p *outboundFileTransport) Check(ctx context.Context) error {
+	return checkFolders(p.settings.Message.Path, p.settings.Attachment.Path, p.settings.ErrorPath, p.settings.SuccessPath, p.settings.DuplicatePath)
+}
+
+// Notify returns a channel receiving a value whenever a file got written to
+// or moved into a watched folder.
+func (p *outboundFileTransport) Notify() <-chan struct{} {
+	if p.watcher == nil {
+		return nil
 	}
+	return p.watcher.notify
+}
 
-	for _, fileInfo := range fileInfos {
-		fileExtension := filepath.Ext(fileInfo.Name())
-		if fileExtension != "" {
-			fileExtension = fileExtension[1:]
-		}
-		filePath := filepath.Join(message.Path, fileInfo.Name())
-		for _, extension := range message.Extensions {
-			if fileExtension == extension {
-				buffer, err := os.ReadFile(filePath)
-				if err != nil {
-					return nil, fmt.Errorf("error while reading attachment %s: %w", filePath, err)
-				}
-				messages = append(messages, transport.Object{
-					Id:      filePath,
-					Content: buffer,
-				})
-			}
-		}
+// Close stops watching for filesystem notifications.
+func (p *outboundFileTransport) Close() error {
+	if p.watcher == nil {
+		return nil
 	}
+	return p.watcher.Close()
+}
 
-	return messages, nil
+// ListMessages lists all messages found within message folder. Each file gets
+// serialized into an transport.Object streaming its content.
+func (p *outboundFileTransport) ListMessages(ctx context.Context) ([]transport.Object, error) {
+	return collect(p.Messages(ctx))
 }
 
 // ListAttachments lists all attachments found within attachment folder. Each file gets
-// serialized into an transport.Object.
+// serialized into an transport.Object streaming its content.
 func (p *outboundFileTransport) ListAttachments(ctx context.Context) ([]transport.Object, error) {
-	attachments := make([]transport.Object, 0)
-	if !p.isAttachmentEnabled() {
-		return attachments, nil
-	}
-	attachment := p.settings.Attachment
-	duration, err := time.ParseDuration(attachment.WaitTime)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse duration: %w", err)
-	}
-	fileInfos, err := p.listFilesLastModifiedBefore(attachment.Path, time.Now().Add(-duration))
-	if err != nil {
-		return nil, fmt.Errorf("failed to list files within %s: %w", attachment.Path, err)
-	}
+	return collect(p.Attachments(ctx))
+}
 
-	for _, fileInfo := range fileInfos {
-		fileExtension := filepath.Ext(fileInfo.Name())
-		if fileExtension != "" {
-			fileExtension = fileExtension[1:]
+// Messages yields the messages found within message folder, oldest first and
+// at most batchSize per call if configured.
+func (p *outboundFileTransport) Messages(ctx context.Context) iter.Seq2[transport.Object, error] {
+	return p.objects(ctx, p.settings.Message, p.isMessageEnabled())
+}
+
+// Attachments yields the attachments found within attachment folder, oldest
+// first and at most batchSize per call if configured.
+func (p *outboundFileTransport) Attachments(ctx context.Context) iter.Seq2[transport.Object, error] {
+	return p.objects(ctx, p.settings.Attachment, p.isAttachmentEnabled())
+}
+
+func (p *outboundFileTransport) objects(ctx context.Context, watch watchSetting, enabled bool) iter.Seq2[transport.Object, error] {
+	return func(yield func(transport.Object, error) bool) {
+		if !enabled {
+			return
 		}
-		filePath := filepath.Join(attachment.Path, fileInfo.Name())
-		for _, extension := range attachment.Extensions {
-			if fileExtension == extension {
-				buffer, err := os.ReadFile(filePath)
-				if err != nil {
-					return nil, fmt.Errorf("error while reading attachment %s: %w", filePath, err)
-				}
-
-				attachments = append(attachments, transport.Object{
-					Id:      filePath,
-					Content: buffer,
-				})
+		duration, err := time.ParseDuration(watch.WaitTime)
+		if err != nil {
+			yield(transport.Object{}, fmt.Errorf("failed to parse duration: %w", err))
+			return
+		}
+		fileInfos, err := p.listFilesLastModifiedBefore(watch.Path, time.Now().Add(-duration))
+		if err != nil {
+			yield(transport.Object{}, fmt.Errorf("failed to list files within %s: %w", watch.Path, err))
+			return
+		}
+
+		count := 0
+		for _, fileInfo := range fileInfos {
+			if !hasExtension(fileInfo.Name(), watch.Extensions) {
+				continue
+			}
+			if p.settings.BatchSize > 0 && count >= p.settings.BatchSize {
+				p.logger.Debug("batch size reached, remaining files are listed with the next run", "folder", watch.Path, "batchSize", p.settings.BatchSize)
+				return
+			}
+			if err := ctx.Err(); err != nil {
+				yield(transport.Object{}, err)
+				return
+			}
+			count++
+			if !yield(fileObject(filepath.Join(watch.Path, fileInfo.Name()), fileInfo), nil) {
+				return
 			}
 		}
 	}
+}
 
-	return attachments, nil
+// hasExtension reports whether the extension of name without its dot is one of extensions.
+func hasExtension(name string, extensions []string) bool {
+	return slices.Contains(extensions, strings.TrimPrefix(filepath.Ext(name), "."))
+}
+
+// collect gathers all objects of seq, stopping at the first error.
+func collect(seq iter.Seq2[transport.Object, error]) ([]transport.Object, error) {
+	objects := make([]transport.Object, 0)
+	for obj, err := range seq {
+		if err != nil {
+			return nil, err
+		}
+		objects = append(objects, obj)
+	}
+	return objects, nil
 }
 
 func (p *outboundFileTransport) Finalize(ctx context.Context, obj transport.Object, err error) error {
 	file := obj.Id
+	if errors.Is(err, transport.ErrDuplicate) && p.settings.DuplicatePath != "" {
+		destination := filepath.Join(p.settings.DuplicatePath, filepath.Base(file))
+		if _, err := move(file, destination); err != nil {
+			return err
+		}
+		p.logger.Info("duplicate file moved", "source", file, "destination", destination)
+		return nil
+	}
 	if err != nil {
 		destination := filepath.Join(p.settings.ErrorPath, filepath.Base(file))
 		if _, err := move(file, destination); err != nil {
@@ -213,16 +277,83 @@ func (p *outboundFileTransport) Finalize(ctx context.Context, obj transport.Obje
 	return nil
 }
 
-// listFilesLastModifiedBefore lists all files last modified before t for path and extension
+// Failed lists the files within the error folder, oldest first. Nothing is
+// listed without error folder, e.g. if only attachments are picked up.
+func (p *outboundFileTransport) Failed(ctx context.Context) ([]transport.FailedObject, error) {
+	if p.settings.ErrorPath == "" {
+		return nil, nil
+	}
+	fileInfos, err := p.listFilesLastModifiedBefore(p.settings.ErrorPath, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	failed := make([]transport.FailedObject, 0, len(fileInfos))
+	for _, fileInfo := range fileInfos {
+		failed = append(failed, transport.FailedObject{
+			Name:    fileInfo.Name(),
+			Size:    fileInfo.Size(),
+			ModTime: fileInfo.ModTime(),
+		})
+	}
+	return failed, nil
+}
+
+// Requeue moves the file name from the error folder back to the message folder.
+// Files only matching the attachment extensions are moved to the attachment folder.
+func (p *outboundFileTransport) Requeue(ctx context.Context, name string) error {
+	if name == "" || filepath.Base(name) != name {
+		return fmt.Errorf("invalid file name %q", name)
+	}
+	ext := strings.TrimPrefix(filepath.Ext(name), ".")
+	destination := p.settings.Message.Path
+	if p.isAttachmentEnabled() && slices.Contains(p.settings.Attachment.Extensions, ext) &&
+		(!p.isMessageEnabled() || !slices.Contains(p.settings.Message.Extensions, ext)) {
+		destination = p.settings.Attachment.Path
+	}
+	if destination == "" {
+		return fmt.Errorf("no outbound folder configured")
+	}
+
+	source := filepath.Join(p.settings.ErrorPath, name)
+	if _, err := os.Stat(source); err != nil {
+		return fmt.Errorf("failed file %s: %w", name, err)
+	}
+	destination = filepath.Join(destination, name)
+	if _, err := os.Stat(destination); err == nil {
+		return fmt.Errorf("file %s already exists", destination)
+	}
+	if _, err := move(source, destination); err != nil {
+		return fmt.Errorf("error while moving file %s: %w", source, err)
+	}
+	p.logger.Info("file requeued", "source", source, "destination", destination)
+	return nil
+}
+
+// fileObject returns an object streaming the content of the file at path.
+func fileObject(path string, info os.FileInfo) transport.Object {
+	return transport.Object{
+		Id:   path,
+		Size: info.Size(),
+		Path: path,
+		Open: func() (io.ReadCloser, error) {
+			return os.Open(path)
+		},
+	}
+}
+
+// listFilesLastModifiedBefore lists all files last modified before t for path and extension.
+// Files reported as completely written by the watcher are listed regardless of t.
 func (p *outboundFileTransport) listFilesLastModifiedBefore(path string, t time.Time) ([]os.FileInfo, error) {
 	files := []os.FileInfo{}
 
 	p.logger.Debug("searching folder for files modified before", "folder", path, "time", t)
 
+	listed := time.Now()
 	dirEntries, err := os.ReadDir(path)
 	if err != nil {
 		return nil, fmt.Errorf("failed to read directory %s: %w", path, err)
 	}
+	defer p.watcher.prune(path, listed)
 
 	for _, dirEntry := range dirEntries {
 		if dirEntry == nil || dirEntry.IsDir() {
@@ -233,7 +364,8 @@ func (p *outboundFileTransport) listFilesLastModifiedBefore(path string, t time.
 		if err != nil {
 			return nil, fmt.Errorf("failed to retrieve file info: %w", err)
 		}
-		if fileInfo.ModTime().Before(t) {
+		ready := p.watcher.take(filepath.Join(path, fileInfo.Name()))
+		if ready || fileInfo.ModTime().Before(t) {
 			files = append(files, fileInfo)
 		}
 	}
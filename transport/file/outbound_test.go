@@ -8,9 +8,11 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
 	"time"
 
+	"github.com/myopenfactory/edi-connector/v2/config"
 	"github.com/myopenfactory/edi-connector/v2/transport"
 	"github.com/myopenfactory/edi-connector/v2/transport/file"
 )
@@ -60,20 +62,58 @@ func TestListMessages(t *testing.T) {
 
 	message := messages[0]
 	expectedContent := []byte("outbound_txt")
-	if !bytes.Equal(message.Content, expectedContent) {
-		t.Errorf("Expected content %s, got: %s", expectedContent, message.Content)
+	if !bytes.Equal(content(t, message), expectedContent) {
+		t.Errorf("Expected content %s, got: %s", expectedContent, content(t, message))
 	}
 
 	message = messages[1]
 	expectedContent = []byte("outbound_csv")
-	if !bytes.Equal(message.Content, expectedContent) {
-		t.Errorf("Expected content %s, got: %s", expectedContent, message.Content)
+	if !bytes.Equal(content(t, message), expectedContent) {
+		t.Errorf("Expected content %s, got: %s", expectedContent, content(t, message))
 	}
 
 	message = messages[2]
 	expectedContent = []byte("outbound_noext")
-	if !bytes.Equal(message.Content, expectedContent) {
-		t.Errorf("Expected content %s, got: %s", expectedContent, message.Content)
+	if !bytes.Equal(content(t, message), expectedContent) {
+		t.Errorf("Expected content %s, got: %s", expectedContent, content(t, message))
+	}
+}
+
+func TestListMessagesBatchSize(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	outboundDir := t.TempDir()
+	for i := range 5 {
+		if err := os.WriteFile(filepath.Join(outboundDir, fmt.Sprintf("outbound_%d.txt", i)), []byte("outbound"), 0644); err != nil {
+			t.Fatalf("Failed to create outbound file: %v", err)
+		}
+	}
+	outbound, err := file.NewOutboundTransport(logger, "12345", "", map[string]any{
+		"message": map[string]any{
+			"path":       outboundDir,
+			"extensions": []string{"txt"},
+			"waitTime":   "0s",
+		},
+		"errorPath": t.TempDir(),
+		"batchSize": 2,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create outbound transport: %v", err)
+	}
+
+	lister, ok := outbound.(transport.Lister)
+	if !ok {
+		t.Fatalf("Expected outbound transport to implement transport.Lister")
+	}
+	count := 0
+	for _, err := range lister.Messages(context.TODO()) {
+		if err != nil {
+			t.Fatalf("Failed to list messages: %v", err)
+		}
+		count++
+	}
+	expectedCount := 2
+	if count != expectedCount {
+		t.Errorf("Expected %d messages, got: %d", expectedCount, count)
 	}
 }
 
@@ -128,20 +168,20 @@ func TestListAttachments(t *testing.T) {
 
 	attachment := attachments[0]
 	expectedContent := []byte("attachment_pdf")
-	if !bytes.Equal(attachment.Content, expectedContent) {
-		t.Errorf("Expected content %s, got: %s", expectedContent, attachment.Content)
+	if !bytes.Equal(content(t, attachment), expectedContent) {
+		t.Errorf("Expected content %s, got: %s", expectedContent, content(t, attachment))
 	}
 
 	attachment = attachments[1]
 	expectedContent = []byte("attachment_step")
-	if !bytes.Equal(attachment.Content, expectedContent) {
-		t.Errorf("Expected content %s, got: %s", expectedContent, attachment.Content)
+	if !bytes.Equal(content(t, attachment), expectedContent) {
+		t.Errorf("Expected content %s, got: %s", expectedContent, content(t, attachment))
 	}
 
 	attachment = attachments[2]
 	expectedContent = []byte("attachment_noext")
-	if !bytes.Equal(attachment.Content, expectedContent) {
-		t.Errorf("Expected content %s, got: %s", expectedContent, attachment.Content)
+	if !bytes.Equal(content(t, attachment), expectedContent) {
+		t.Errorf("Expected content %s, got: %s", expectedContent, content(t, attachment))
 	}
 }
 
@@ -296,3 +336,173 @@ func TestFinalizeOnError(t *testing.T) {
 		t.Errorf("Expected %d success entries, got: %d", 0, len(successEntries))
 	}
 }
+
+func TestFinalizeOnDuplicate(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	outboundDir := t.TempDir()
+	outboundFilepath := filepath.Join(outboundDir, "outbound.txt")
+	if err := os.WriteFile(outboundFilepath, []byte("outbound_txt"), 0644); err != nil {
+		t.Fatalf("Failed to create outbound file: %v", err)
+	}
+	errorDir := t.TempDir()
+	duplicateDir := t.TempDir()
+	outbound, err := file.NewOutboundTransport(logger, "12345", "", map[string]any{
+		"message": map[string]any{
+			"path":       outboundDir,
+			"extensions": []string{"txt"},
+		},
+		"errorPath":     errorDir,
+		"duplicatePath": duplicateDir,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create outbound transport: %v", err)
+	}
+
+	finalizer, ok := outbound.(transport.Finalizer)
+	if !ok {
+		t.Fatal("Expected finalizer")
+	}
+
+	err = finalizer.Finalize(context.TODO(), transport.Object{
+		Id: outboundFilepath,
+	}, fmt.Errorf("upload skipped: %w", transport.ErrDuplicate))
+	if err != nil {
+		t.Fatalf("Failed to finalize outbound transport: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(duplicateDir, "outbound.txt")); os.IsNotExist(err) {
+		t.Error("Expected file to be moved into duplicate folder but did not find it")
+	}
+
+	errorEntries, err := os.ReadDir(errorDir)
+	if err != nil {
+		t.Fatalf("Failed to list error dir: %v", err)
+	}
+	if len(errorEntries) != 0 {
+		t.Errorf("Expected %d error entries, got: %d", 0, len(errorEntries))
+	}
+}
+
+func content(t *testing.T, obj transport.Object) []byte {
+	t.Helper()
+	data, err := obj.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to read content of %s: %v", obj.Id, err)
+	}
+	return data
+}
+
+func TestRequeue(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	messageDir := t.TempDir()
+	attachmentDir := t.TempDir()
+	errorDir := t.TempDir()
+	for _, name := range []string{"message.txt", "attachment.pdf"} {
+		if err := os.WriteFile(filepath.Join(errorDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to create error file: %v", err)
+		}
+	}
+	outbound, err := file.NewOutboundTransport(logger, "12345", "", map[string]any{
+		"message": map[string]any{
+			"path":       messageDir,
+			"extensions": []string{"txt"},
+		},
+		"attachment": map[string]any{
+			"path":       attachmentDir,
+			"extensions": []string{"pdf"},
+		},
+		"errorPath": errorDir,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create outbound transport: %v", err)
+	}
+	requeuer := outbound.(transport.Requeuer)
+
+	failed, err := requeuer.Failed(context.TODO())
+	if err != nil {
+		t.Fatalf("Failed to list failed files: %v", err)
+	}
+	if len(failed) != 2 {
+		t.Fatalf("Expected 2 failed files, got: %d", len(failed))
+	}
+
+	for _, name := range []string{"message.txt", "attachment.pdf"} {
+		if err := requeuer.Requeue(context.TODO(), name); err != nil {
+			t.Errorf("Failed to requeue %s: %v", name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(messageDir, "message.txt")); err != nil {
+		t.Errorf("Expected message within message folder, got: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(attachmentDir, "attachment.pdf")); err != nil {
+		t.Errorf("Expected attachment within attachment folder, got: %v", err)
+	}
+	if err := requeuer.Requeue(context.TODO(), "../message.txt"); err == nil {
+		t.Errorf("Expected error for name outside of error folder")
+	}
+}
+
+func TestValidateOutbound(t *testing.T) {
+	messageDir := t.TempDir()
+	problems := transport.ValidateOutbound("$.outbounds[0]", config.ProcessConfig{
+		Id:   "out",
+		Type: "FILE",
+		Settings: map[string]any{
+			"message": map[string]any{
+				"path":       messageDir,
+				"extensions": []string{"xml"},
+				"waitTime":   "soon",
+			},
+			"errorPath":  filepath.Join(messageDir, "missing"),
+			"sucessPath": messageDir,
+		},
+	})
+
+	var paths []string
+	for _, problem := range problems {
+		paths = append(paths, problem.Path)
+	}
+	expected := []string{
+		"$.outbounds[0].settings.sucessPath",
+		"$.outbounds[0].settings.message.waitTime",
+		"$.outbounds[0].settings.errorPath",
+	}
+	if !slices.Equal(paths, expected) {
+		t.Errorf("Expected problems at %v, got: %v", expected, problems)
+	}
+}
+
+func TestValidateLeavesFoldersUntouched(t *testing.T) {
+	messageDir := t.TempDir()
+	errorDir := t.TempDir()
+	// creating and removing a file would update the modification time
+	modTime := time.Now().Add(-time.Hour).Truncate(time.Second)
+	for _, dir := range []string{messageDir, errorDir} {
+		if err := os.Chtimes(dir, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+	}
+	problems := transport.ValidateOutbound("$.outbounds[0]", config.ProcessConfig{
+		Id:   "12345",
+		Type: "FILE",
+		Settings: map[string]any{
+			"message": map[string]any{
+				"path":       messageDir,
+				"extensions": []string{"txt"},
+			},
+			"errorPath": errorDir,
+		},
+	})
+	if len(problems) != 0 {
+		t.Errorf("Expected no problems, got: %v", problems)
+	}
+	for _, dir := range []string{messageDir, errorDir} {
+		info, err := os.Stat(dir)
+		if err != nil {
+			t.Fatalf("Failed to stat %s: %v", dir, err)
+		}
+		if !info.ModTime().Equal(modTime) {
+			t.Errorf("Expected %s to stay untouched, modified at: %v", dir, info.ModTime())
+		}
+	}
+}
@@ -0,0 +1,20 @@
+package file
+
+import (
+	"log/slog"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+)
+
+func init() {
+	transport.RegisterOutbound("FILE", func(logger *slog.Logger, pc config.ProcessConfig, _ credentials.CredManager) (transport.OutboundTransport, error) {
+		return NewOutboundTransport(logger, pc.Id, pc.AuthName, pc.Settings)
+	})
+	transport.RegisterInbound("FILE", func(logger *slog.Logger, pc config.ProcessConfig, _ credentials.CredManager) (transport.InboundTransport, error) {
+		return NewInboundTransport(logger, pc.Id, pc.AuthName, pc.Settings)
+	})
+	transport.RegisterOutboundValidator("FILE", validateOutbound)
+	transport.RegisterInboundValidator("FILE", validateInbound)
+}
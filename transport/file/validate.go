@@ -0,0 +1,74 @@
+package file
+
+import (
+	"github.com/myopenfactory/edi-connector/v2/config"
+)
+
+// validateOutbound returns the problems of the outbound file settings at path.
+func validateOutbound(path string, cfg map[string]any) []config.Problem {
+	problems := config.UnknownFields(path, cfg, outboundFileSettings{})
+	var settings outboundFileSettings
+	if err := config.Decode(cfg, &settings); err != nil {
+		return append(problems, config.Problemf(path, "failed to decode outbound file settings: %v", err))
+	}
+
+	folder := func(field, folder string, required bool) {
+		if folder == "" {
+			if required {
+				problems = append(problems, config.Problemf(path+"."+field, "folder required"))
+			}
+			return
+		}
+		if err := checkWritableFolder(folder); err != nil {
+			problems = append(problems, config.Problemf(path+"."+field, "%v", err))
+		}
+	}
+	watch := func(field string, watch watchSetting) {
+		if watch.Path == "" {
+			return
+		}
+		folder(field+".path", watch.Path, true)
+		if len(watch.Extensions) == 0 {
+			problems = append(problems, config.Problemf(path+"."+field+".extensions", "no extensions configured, no files are picked up"))
+		}
+		problems = append(problems, config.CheckDuration(path+"."+field+".waitTime", watch.WaitTime)...)
+	}
+
+	watch("message", settings.Message)
+	watch("attachment", settings.Attachment)
+	if settings.Message.Path != "" {
+		folder("errorPath", settings.ErrorPath, true)
+		folder("successPath", settings.SuccessPath, false)
+		folder("duplicatePath", settings.DuplicatePath, false)
+	}
+	if settings.BatchSize < 0 {
+		problems = append(problems, config.Problemf(path+".batchSize", "must not be negative"))
+	}
+	return problems
+}
+
+// validateInbound returns the problems of the inbound file settings at path.
+func validateInbound(path string, cfg map[string]any) []config.Problem {
+	problems := config.UnknownFields(path, cfg, inboundFileSettings{})
+	var settings inboundFileSettings
+	if err := config.Decode(cfg, &settings); err != nil {
+		return append(problems, config.Problemf(path, "failed to decode inbound file settings: %v", err))
+	}
+
+	if settings.Path == "" {
+		problems = append(problems, config.Problemf(path+".path", "folder required"))
+	} else if err := checkWritableFolder(settings.Path); err != nil {
+		problems = append(problems, config.Problemf(path+".path", "%v", err))
+	}
+	if settings.AttachmentPath != "" {
+		if err := checkWritableFolder(settings.AttachmentPath); err != nil {
+			problems = append(problems, config.Problemf(path+".attachmentPath", "%v", err))
+		}
+	}
+	switch settings.Mode {
+	case "", "create", "append":
+	default:
+		problems = append(problems, config.Problemf(path+".mode", "unknown mode %q, expected create or append", settings.Mode))
+	}
+	return problems
+}
@@ -0,0 +1,70 @@
+package file
+
+import (
+	"log/slog"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// watcher receives filesystem notifications for files completely written to
+// or moved into the watched folders.
+type watcher struct {
+	logger *slog.Logger
+	notify chan struct{}
+	closer func() error
+	// folders maps the watched folders as configured to their clean form
+	folders map[string]string
+
+	mu sync.Mutex
+	// ready holds the time files were reported as complete
+	ready map[string]time.Time
+}
+
+// markReady remembers the file at path as complete and wakes up the listener.
+func (w *watcher) markReady(path string) {
+	w.mu.Lock()
+	w.ready[path] = time.Now()
+	w.mu.Unlock()
+
+	select {
+	case w.notify <- struct{}{}:
+	default:
+	}
+}
+
+// take reports whether the file at path was reported as complete and forgets it.
+func (w *watcher) take(path string) bool {
+	if w == nil {
+		return false
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, ok := w.ready[path]
+	delete(w.ready, path)
+	return ok
+}
+
+// prune forgets the files within folder reported as complete before t. Called
+// after a listing started at t took all listed files, the remaining files were
+// renamed or deleted in the meantime.
+func (w *watcher) prune(folder string, t time.Time) {
+	if w == nil {
+		return
+	}
+	clean, ok := w.folders[folder]
+	if !ok {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for path, marked := range w.ready {
+		if filepath.Dir(path) == clean && marked.Before(t) {
+			delete(w.ready, path)
+		}
+	}
+}
+
+func (w *watcher) Close() error {
+	return w.closer()
+}
@@ -0,0 +1,109 @@
+//go:build linux
+
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"log/slog"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// filesystems whose remote changes aren't reported by inotify
+var networkFilesystems = map[int64]string{
+	0x6969:     "nfs",
+	0x517b:     "smb",
+	0xff534d42: "cifs",
+	0xfe534d42: "smb2",
+	0x65735546: "fuse",
+}
+
+// newWatcher watches the folders for files with one of their extensions
+// closed after writing or moved in using inotify.
+func newWatcher(logger *slog.Logger, folders map[string][]string) (*watcher, error) {
+	for path := range folders {
+		var stat unix.Statfs_t
+		if err := unix.Statfs(path, &stat); err != nil {
+			return nil, fmt.Errorf("failed to determine filesystem of %s: %w", path, err)
+		}
+		if name, ok := networkFilesystems[int64(stat.Type)]; ok {
+			return nil, fmt.Errorf("folder %s is located on a %s filesystem", path, name)
+		}
+	}
+
+	fd, err := unix.InotifyInit1(unix.IN_CLOEXEC | unix.IN_NONBLOCK)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize inotify: %w", err)
+	}
+	// a non blocking file is registered with the runtime poller, so Close
+	// interrupts a pending Read
+	file := os.NewFile(uintptr(fd), "inotify")
+
+	watches := make(map[int32]string)
+	clean := make(map[string]string)
+	for path := range folders {
+		clean[path] = filepath.Clean(path)
+		wd, err := unix.InotifyAddWatch(fd, path, unix.IN_CLOSE_WRITE|unix.IN_MOVED_TO)
+		if err != nil {
+			file.Close()
+			return nil, fmt.Errorf("failed to watch %s: %w", path, err)
+		}
+		watches[int32(wd)] = path
+	}
+
+	w := &watcher{
+		logger:  logger,
+		notify:  make(chan struct{}, 1),
+		closer:  file.Close,
+		folders: clean,
+		ready:   make(map[string]time.Time),
+	}
+	go w.read(file, watches, folders)
+	return w, nil
+}
+
+// read reports the files of events on the watches as ready. If reading fails
+// no further notifications are sent and the folders are polled only.
+func (w *watcher) read(file *os.File, watches map[int32]string, folders map[string][]string) {
+	buffer := make([]byte, 64*(unix.SizeofInotifyEvent+unix.NAME_MAX+1))
+	for {
+		n, err := file.Read(buffer)
+		if errors.Is(err, os.ErrClosed) {
+			return
+		}
+		if err != nil {
+			w.logger.Error("failed to read filesystem notifications, falling back to polling", "folders", slices.Collect(maps.Keys(folders)), "error", err)
+			file.Close()
+			return
+		}
+
+		for offset := 0; offset+unix.SizeofInotifyEvent <= n; {
+			event := (*unix.InotifyEvent)(unsafe.Pointer(&buffer[offset]))
+			name := buffer[offset+unix.SizeofInotifyEvent : offset+unix.SizeofInotifyEvent+int(event.Len)]
+			offset += unix.SizeofInotifyEvent + int(event.Len)
+
+			if event.Mask&unix.IN_Q_OVERFLOW != 0 {
+				w.logger.Warn("filesystem notifications overflowed, waiting for next poll")
+				continue
+			}
+			folder, ok := watches[event.Wd]
+			if !ok || event.Mask&unix.IN_ISDIR != 0 || len(name) == 0 {
+				continue
+			}
+			path := filepath.Join(folder, string(bytes.TrimRight(name, "\x00")))
+			if !hasExtension(path, folders[folder]) {
+				continue
+			}
+			w.logger.Debug("file ready", "path", path)
+			w.markReady(path)
+		}
+	}
+}
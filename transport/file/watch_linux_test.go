@@ -0,0 +1,64 @@
+//go:build linux
+
+package file_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/transport"
+	"github.com/myopenfactory/edi-connector/v2/transport/file"
+)
+
+func TestWatchMessages(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	outboundDir := t.TempDir()
+	outbound, err := file.NewOutboundTransport(logger, "12345", "", map[string]any{
+		"message": map[string]any{
+			"path":       outboundDir,
+			"extensions": []string{"txt"},
+			"waitTime":   "1h",
+		},
+		"errorPath": t.TempDir(),
+		"watch":     true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create outbound transport: %v", err)
+	}
+	defer outbound.(io.Closer).Close()
+
+	notifier, ok := outbound.(transport.Notifier)
+	if !ok || notifier.Notify() == nil {
+		t.Fatal("Expected notifier")
+	}
+
+	if err := os.WriteFile(filepath.Join(outboundDir, "outbound.txt"), []byte("outbound_txt"), 0644); err != nil {
+		t.Fatalf("Failed to create outbound file: %v", err)
+	}
+	select {
+	case <-notifier.Notify():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected notification for written file")
+	}
+
+	messages, err := outbound.ListMessages(context.TODO())
+	if err != nil {
+		t.Fatalf("Failed to list messages: %v", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("Expected %d messages, got: %d", 1, len(messages))
+	}
+
+	messages, err = outbound.ListMessages(context.TODO())
+	if err != nil {
+		t.Fatalf("Failed to list messages: %v", err)
+	}
+	if len(messages) != 0 {
+		t.Errorf("Expected %d messages after pickup, got: %d", 0, len(messages))
+	}
+}
@@ -0,0 +1,12 @@
+//go:build !linux
+
+package file
+
+import (
+	"fmt"
+	"log/slog"
+)
+
+func newWatcher(logger *slog.Logger, folders map[string][]string) (*watcher, error) {
+	return nil, fmt.Errorf("filesystem notifications are not supported on this platform")
+}
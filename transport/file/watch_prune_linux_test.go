@@ -0,0 +1,63 @@
+//go:build linux
+
+package file
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestWatchForgetsVanishedFiles(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	outboundDir := t.TempDir()
+	outbound, err := NewOutboundTransport(logger, "12345", "", map[string]any{
+		"message": map[string]any{
+			// the folder is configured in an unclean form
+			"path":       outboundDir + string(filepath.Separator),
+			"extensions": []string{"txt"},
+			"waitTime":   "1h",
+		},
+		"errorPath": t.TempDir(),
+		"watch":     true,
+	})
+	if err != nil {
+		t.Fatalf("Failed to create outbound transport: %v", err)
+	}
+	p := outbound.(*outboundFileTransport)
+	defer p.Close()
+
+	// files of other extensions are ignored, deleted files are forgotten with the next listing
+	for _, name := range []string{"ignored.tmp", "deleted.txt"} {
+		if err := os.WriteFile(filepath.Join(outboundDir, name), []byte(name), 0644); err != nil {
+			t.Fatalf("Failed to create file: %v", err)
+		}
+	}
+	select {
+	case <-p.Notify():
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected notification for written file")
+	}
+	if err := os.Remove(filepath.Join(outboundDir, "deleted.txt")); err != nil {
+		t.Fatalf("Failed to delete file: %v", err)
+	}
+
+	p.watcher.mu.Lock()
+	if _, ok := p.watcher.ready[filepath.Join(outboundDir, "ignored.tmp")]; ok {
+		t.Error("Expected file with other extension to be ignored")
+	}
+	p.watcher.mu.Unlock()
+
+	if _, err := p.ListMessages(context.TODO()); err != nil {
+		t.Fatalf("Failed to list messages: %v", err)
+	}
+	p.watcher.mu.Lock()
+	defer p.watcher.mu.Unlock()
+	if len(p.watcher.ready) != 0 {
+		t.Errorf("Expected no files to be remembered, got: %v", p.watcher.ready)
+	}
+}
@@ -0,0 +1,344 @@
+package plugin
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+)
+
+// ErrPluginExited is returned for calls which were in flight when the plugin process terminated.
+var ErrPluginExited = errors.New("plugin process exited")
+
+type pluginSettings struct {
+	Command           string            `json:"command" yaml:"command"`
+	Args              []string          `json:"args" yaml:"args"`
+	Env               map[string]string `json:"env" yaml:"env"`
+	Dir               string            `json:"dir" yaml:"dir"`
+	CallTimeout       string            `json:"callTimeout" yaml:"callTimeout"`
+	RestartBackoff    string            `json:"restartBackoff" yaml:"restartBackoff"`
+	MaxRestartBackoff string            `json:"maxRestartBackoff" yaml:"maxRestartBackoff"`
+	Settings          map[string]any    `json:"settings" yaml:"settings"`
+}
+
+// client manages the plugin process. The process is started on the first
+// call and restarted with exponential backoff after it terminated.
+type client struct {
+	logger     *slog.Logger
+	settings   pluginSettings
+	initParams InitializeParams
+
+	callTimeout       time.Duration
+	restartBackoff    time.Duration
+	maxRestartBackoff time.Duration
+
+	mu        sync.Mutex
+	proc      *process
+	failures  int
+	nextStart time.Time
+	closed    bool
+}
+
+// process is a single running instance of the plugin.
+type process struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	writeMu sync.Mutex
+
+	mu      sync.Mutex
+	nextId  int64
+	pending map[int64]chan Response
+
+	// done is closed once the process terminated
+	done   chan struct{}
+	err    error
+	exited time.Time
+}
+
+func newClient(logger *slog.Logger, settings pluginSettings, initParams InitializeParams) (*client, error) {
+	if settings.Command == "" {
+		return nil, fmt.Errorf("no plugin command provided")
+	}
+	if settings.CallTimeout == "" {
+		settings.CallTimeout = "30s"
+	}
+	if settings.RestartBackoff == "" {
+		settings.RestartBackoff = "1s"
+	}
+	if settings.MaxRestartBackoff == "" {
+		settings.MaxRestartBackoff = "5m"
+	}
+	callTimeout, err := time.ParseDuration(settings.CallTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse callTimeout: %w", err)
+	}
+	restartBackoff, err := time.ParseDuration(settings.RestartBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse restartBackoff: %w", err)
+	}
+	maxRestartBackoff, err := time.ParseDuration(settings.MaxRestartBackoff)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse maxRestartBackoff: %w", err)
+	}
+
+	initParams.ProtocolVersion = ProtocolVersion
+	initParams.Settings = settings.Settings
+	return &client{
+		logger:            logger,
+		settings:          settings,
+		initParams:        initParams,
+		callTimeout:       callTimeout,
+		restartBackoff:    restartBackoff,
+		maxRestartBackoff: maxRestartBackoff,
+	}, nil
+}
+
+// call invokes method on the plugin and decodes the result into result. The
+// call is bound to ctx, if ctx carries no deadline the configured call timeout
+// is applied. A plugin which exceeds the deadline is considered hung and killed.
+func (c *client) call(ctx context.Context, method string, params any, result any) error {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.callTimeout)
+		defer cancel()
+	}
+
+	proc, err := c.running(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = proc.call(ctx, method, params, result)
+	if errors.Is(err, context.DeadlineExceeded) {
+		c.logger.Error("plugin call timed out, killing plugin", "command", c.settings.Command, "method", method)
+		proc.kill()
+	}
+	if err == nil {
+		c.mu.Lock()
+		c.failures = 0
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// running returns the running plugin process and starts it if required.
+func (c *client) running(ctx context.Context) (*process, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, fmt.Errorf("plugin client closed")
+	}
+	if c.proc != nil {
+		select {
+		case <-c.proc.done:
+			backoff := c.backoff(c.proc.exited)
+			c.logger.Error("plugin terminated", "command", c.settings.Command, "error", c.proc.err, "restartIn", time.Until(c.nextStart).Round(time.Millisecond), "backoff", backoff)
+			c.proc = nil
+		default:
+			return c.proc, nil
+		}
+	}
+
+	if wait := time.Until(c.nextStart); wait > 0 {
+		return nil, fmt.Errorf("plugin %s crashed, restart in %s", c.settings.Command, wait.Round(time.Millisecond))
+	}
+
+	proc, err := c.start()
+	if err != nil {
+		c.backoff(time.Now())
+		return nil, err
+	}
+
+	var initResult InitializeResult
+	if err := proc.call(ctx, MethodInitialize, c.initParams, &initResult); err != nil {
+		proc.kill()
+		<-proc.done
+		c.backoff(time.Now())
+		return nil, fmt.Errorf("failed to initialize plugin: %w", err)
+	}
+	if initResult.ProtocolVersion != ProtocolVersion {
+		proc.kill()
+		<-proc.done
+		return nil, fmt.Errorf("unsupported plugin protocol version %d, expected %d", initResult.ProtocolVersion, ProtocolVersion)
+	}
+
+	c.logger.Info("plugin started", "command", c.settings.Command, "pid", proc.cmd.Process.Pid)
+	c.proc = proc
+	return proc, nil
+}
+
+// backoff records a failure at t and returns the delay until the next start
+// attempt. c.mu has to be held by the caller.
+func (c *client) backoff(t time.Time) time.Duration {
+	c.failures++
+	backoff := min(c.restartBackoff<<min(c.failures-1, 30), c.maxRestartBackoff)
+	c.nextStart = t.Add(backoff)
+	return backoff
+}
+
+func (c *client) start() (*process, error) {
+	cmd := exec.Command(c.settings.Command, c.settings.Args...)
+	cmd.Dir = c.settings.Dir
+	cmd.Env = os.Environ()
+	for key, value := range c.settings.Env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin stdin: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin stdout: %w", err)
+	}
+	stderr, err := cmd.StderrPipe()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open plugin stderr: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start plugin %s: %w", c.settings.Command, err)
+	}
+
+	proc := &process{
+		cmd:     cmd,
+		stdin:   stdin,
+		pending: make(map[int64]chan Response),
+		done:    make(chan struct{}),
+	}
+
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		scanner := bufio.NewScanner(stderr)
+		for scanner.Scan() {
+			c.logger.Info("plugin output", "command", c.settings.Command, "line", scanner.Text())
+		}
+	})
+	go func() {
+		readErr := proc.read(stdout)
+		wg.Wait()
+		waitErr := cmd.Wait()
+		proc.mu.Lock()
+		proc.err = errors.Join(readErr, waitErr)
+		proc.exited = time.Now()
+		for id, ch := range proc.pending {
+			close(ch)
+			delete(proc.pending, id)
+		}
+		proc.mu.Unlock()
+		close(proc.done)
+	}()
+
+	return proc, nil
+}
+
+// Close terminates the plugin process.
+func (c *client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.proc == nil {
+		return nil
+	}
+	c.proc.stdin.Close()
+	select {
+	case <-c.proc.done:
+	case <-time.After(5 * time.Second):
+		c.proc.kill()
+		<-c.proc.done
+	}
+	c.proc = nil
+	return nil
+}
+
+// read dispatches responses to the waiting calls until stdout is closed.
+func (p *process) read(stdout io.Reader) error {
+	scanner := bufio.NewScanner(stdout)
+	scanner.Buffer(make([]byte, 64*1024), 1<<30)
+	for scanner.Scan() {
+		var response Response
+		if err := json.Unmarshal(scanner.Bytes(), &response); err != nil {
+			p.kill()
+			return fmt.Errorf("invalid plugin response: %w", err)
+		}
+		p.mu.Lock()
+		ch, ok := p.pending[response.Id]
+		delete(p.pending, response.Id)
+		p.mu.Unlock()
+		if ok {
+			ch <- response
+		}
+	}
+	return scanner.Err()
+}
+
+func (p *process) call(ctx context.Context, method string, params any, result any) error {
+	request := Request{
+		JsonRPC: "2.0",
+		Method:  method,
+	}
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return fmt.Errorf("failed to encode %s params: %w", method, err)
+		}
+		request.Params = data
+	}
+
+	ch := make(chan Response, 1)
+	p.mu.Lock()
+	p.nextId++
+	request.Id = p.nextId
+	p.pending[request.Id] = ch
+	p.mu.Unlock()
+
+	data, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s request: %w", method, err)
+	}
+	p.writeMu.Lock()
+	_, err = p.stdin.Write(append(data, '\n'))
+	p.writeMu.Unlock()
+	if err != nil {
+		p.forget(request.Id)
+		return fmt.Errorf("failed to send %s request: %w", method, err)
+	}
+
+	select {
+	case response, ok := <-ch:
+		if !ok {
+			return fmt.Errorf("%s: %w", method, ErrPluginExited)
+		}
+		if response.Error != nil {
+			return fmt.Errorf("%s: %w", method, response.Error)
+		}
+		if result != nil {
+			if err := json.Unmarshal(response.Result, result); err != nil {
+				return fmt.Errorf("failed to decode %s result: %w", method, err)
+			}
+		}
+		return nil
+	case <-ctx.Done():
+		p.forget(request.Id)
+		return fmt.Errorf("%s: %w", method, ctx.Err())
+	}
+}
+
+func (p *process) forget(id int64) {
+	p.mu.Lock()
+	delete(p.pending, id)
+	p.mu.Unlock()
+}
+
+func (p *process) kill() {
+	if p.cmd.Process != nil {
+		p.cmd.Process.Kill()
+	}
+}
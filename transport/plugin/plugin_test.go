@@ -0,0 +1,170 @@
+package plugin_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/transport"
+	"github.com/myopenfactory/edi-connector/v2/transport/plugin"
+)
+
+// TestMain lets the test binary act as plugin when started by the transport.
+func TestMain(m *testing.M) {
+	if os.Getenv("EDI_CONNECTOR_TEST_PLUGIN") == "1" {
+		servePlugin()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// servePlugin implements a minimal plugin. Messages carrying the metadata
+// "crash" terminate the plugin, messages carrying "hang" never get an answer.
+func servePlugin() {
+	scanner := bufio.NewScanner(os.Stdin)
+	encoder := json.NewEncoder(os.Stdout)
+	for scanner.Scan() {
+		var request plugin.Request
+		if err := json.Unmarshal(scanner.Bytes(), &request); err != nil {
+			os.Exit(2)
+		}
+		response := plugin.Response{JsonRPC: "2.0", Id: request.Id}
+		var result any
+		switch request.Method {
+		case plugin.MethodInitialize:
+			result = plugin.InitializeResult{ProtocolVersion: plugin.ProtocolVersion}
+		case plugin.MethodListMessages:
+			result = []plugin.Object{{Id: "1", Content: []byte("message")}}
+		case plugin.MethodListAttachments:
+			result = []plugin.Object{}
+		case plugin.MethodProcessMessage:
+			var obj plugin.Object
+			json.Unmarshal(request.Params, &obj)
+			if _, ok := obj.Metadata["crash"]; ok {
+				os.Exit(3)
+			}
+			if _, ok := obj.Metadata["hang"]; ok {
+				continue
+			}
+			if _, ok := obj.Metadata["fail"]; ok {
+				response.Error = &plugin.Error{Code: 1, Message: "rejected"}
+				break
+			}
+			result = plugin.ProcessMessageResult{Status: fmt.Sprintf("Stored %s: %s", obj.Id, obj.Content)}
+		case plugin.MethodHandleAttachment:
+			result = plugin.HandleAttachmentResult{Handle: true}
+		default:
+			result = struct{}{}
+		}
+		if result != nil {
+			response.Result, _ = json.Marshal(result)
+		}
+		encoder.Encode(response)
+	}
+}
+
+func newInbound(t *testing.T) transport.InboundTransport {
+	t.Helper()
+	t.Setenv("EDI_CONNECTOR_TEST_PLUGIN", "1")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	inbound, err := plugin.NewInboundTransport(logger, "12345", "", map[string]any{
+		"command":        os.Args[0],
+		"restartBackoff": "10ms",
+	})
+	if err != nil {
+		t.Fatalf("Failed to create inbound plugin transport: %v", err)
+	}
+	t.Cleanup(func() { inbound.(io.Closer).Close() })
+	return inbound
+}
+
+func TestListMessages(t *testing.T) {
+	t.Setenv("EDI_CONNECTOR_TEST_PLUGIN", "1")
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	outbound, err := plugin.NewOutboundTransport(logger, "12345", "", map[string]any{
+		"command": os.Args[0],
+	})
+	if err != nil {
+		t.Fatalf("Failed to create outbound plugin transport: %v", err)
+	}
+	defer outbound.(io.Closer).Close()
+
+	messages, err := outbound.ListMessages(t.Context())
+	if err != nil {
+		t.Fatalf("Failed to list messages: %v", err)
+	}
+	if len(messages) != 1 {
+		t.Fatalf("Expected %d messages, got: %d", 1, len(messages))
+	}
+	if string(messages[0].Content) != "message" {
+		t.Errorf("Expected content %s, got: %s", "message", messages[0].Content)
+	}
+
+	if err := outbound.(transport.Finalizer).Finalize(t.Context(), messages[0], nil); err != nil {
+		t.Errorf("Failed to finalize message: %v", err)
+	}
+}
+
+func TestProcessMessage(t *testing.T) {
+	inbound := newInbound(t)
+
+	status, err := inbound.ProcessMessage(t.Context(), transport.Object{Id: "1", Content: []byte("test")})
+	if err != nil {
+		t.Fatalf("Failed to process message: %v", err)
+	}
+	if expected := "Stored 1: test"; status != expected {
+		t.Errorf("Expected status %q, got: %q", expected, status)
+	}
+
+	var pluginErr *plugin.Error
+	_, err = inbound.ProcessMessage(t.Context(), transport.Object{Id: "2", Metadata: map[string]string{"fail": ""}})
+	if !errors.As(err, &pluginErr) {
+		t.Errorf("Expected plugin error, got: %v", err)
+	}
+
+	if !inbound.HandleAttachment("https://myopenfactory.net/") {
+		t.Error("Expected attachment to be handled")
+	}
+}
+
+func TestRestartAfterCrash(t *testing.T) {
+	inbound := newInbound(t)
+
+	_, err := inbound.ProcessMessage(t.Context(), transport.Object{Id: "1", Metadata: map[string]string{"crash": ""}})
+	if !errors.Is(err, plugin.ErrPluginExited) {
+		t.Fatalf("Expected plugin exited error, got: %v", err)
+	}
+
+	_, err = inbound.ProcessMessage(t.Context(), transport.Object{Id: "2"})
+	if err == nil {
+		t.Fatal("Expected error while plugin restart is backed off")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if _, err := inbound.ProcessMessage(t.Context(), transport.Object{Id: "3"}); err != nil {
+		t.Errorf("Expected restarted plugin to process message, got: %v", err)
+	}
+}
+
+func TestCallTimeout(t *testing.T) {
+	inbound := newInbound(t)
+
+	ctx, cancel := context.WithTimeout(t.Context(), 200*time.Millisecond)
+	defer cancel()
+	_, err := inbound.ProcessMessage(ctx, transport.Object{Id: "1", Metadata: map[string]string{"hang": ""}})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected deadline exceeded, got: %v", err)
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	if _, err := inbound.ProcessMessage(t.Context(), transport.Object{Id: "2"}); err != nil {
+		t.Errorf("Expected restarted plugin to process message, got: %v", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Package plugin implements the PLUGIN transport which delegates inbound and
+// outbound processing to an external executable.
+//
+// The connector starts the configured command and speaks JSON-RPC 2.0 with it,
+// one JSON document per line, requests on the plugin's stdin and responses on
+// its stdout. Anything the plugin writes to stderr is forwarded to the log.
+//
+// The first request is always "initialize" carrying the protocol version, the
+// process configuration and the plugin specific settings. The plugin has to
+// answer with the protocol version it speaks, which must match ProtocolVersion.
+// Afterwards the connector issues the following methods:
+//
+//	listMessages, listAttachments      -> []Object
+//	processMessage(Object)             -> {"status": string}
+//	processAttachment(Object)          -> {}
+//	handleAttachment({"url": string})  -> {"handle": bool}
+//	finalize({"object", "error"})      -> {}
+//
+// Object content is transferred base64 encoded. Errors are reported through
+// the JSON-RPC error member and are passed on to the connector.
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ProtocolVersion is the version of the plugin protocol spoken by the connector.
+const ProtocolVersion = 1
+
+const (
+	MethodInitialize        = "initialize"
+	MethodListMessages      = "listMessages"
+	MethodListAttachments   = "listAttachments"
+	MethodProcessMessage    = "processMessage"
+	MethodProcessAttachment = "processAttachment"
+	MethodHandleAttachment  = "handleAttachment"
+	MethodFinalize          = "finalize"
+)
+
+// Request is a JSON-RPC request sent to the plugin.
+type Request struct {
+	JsonRPC string          `json:"jsonrpc"`
+	Id      int64           `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+// Response is a JSON-RPC response returned by the plugin.
+type Response struct {
+	JsonRPC string          `json:"jsonrpc"`
+	Id      int64           `json:"id"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("plugin error %d: %s", e.Code, e.Message)
+}
+
+// Object is the wire representation of transport.Object.
+type Object struct {
+	Id       string            `json:"id"`
+	Content  []byte            `json:"content"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// InitializeParams are sent with the initialize request.
+type InitializeParams struct {
+	ProtocolVersion int            `json:"protocolVersion"`
+	Direction       string         `json:"direction"`
+	ConfigId        string         `json:"configId"`
+	AuthName        string         `json:"authName"`
+	Settings        map[string]any `json:"settings,omitempty"`
+}
+
+// InitializeResult is expected as answer to the initialize request.
+type InitializeResult struct {
+	ProtocolVersion int `json:"protocolVersion"`
+}
+
+// ProcessMessageResult is expected as answer to the processMessage request.
+type ProcessMessageResult struct {
+	Status string `json:"status"`
+}
+
+// HandleAttachmentParams are sent with the handleAttachment request.
+type HandleAttachmentParams struct {
+	Url string `json:"url"`
+}
+
+// HandleAttachmentResult is expected as answer to the handleAttachment request.
+type HandleAttachmentResult struct {
+	Handle bool `json:"handle"`
+}
+
+// FinalizeParams are sent with the finalize request. Error is empty if the
+// object was processed successfully.
+type FinalizeParams struct {
+	Object Object `json:"object"`
+	Error  string `json:"error,omitempty"`
+}
@@ -0,0 +1,181 @@
+package plugin
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+)
+
+type outboundPluginTransport struct {
+	configId string
+	authName string
+	client   *client
+}
+
+// NewOutboundTransport returns new OutTransport delegating to the configured plugin executable.
+func NewOutboundTransport(logger *slog.Logger, configId, authName string, cfg map[string]any) (transport.OutboundTransport, error) {
+	client, err := newPluginClient(logger, "outbound", configId, authName, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &outboundPluginTransport{
+		configId: configId,
+		authName: authName,
+		client:   client,
+	}, nil
+}
+
+func (p *outboundPluginTransport) ConfigId() string {
+	return p.configId
+}
+
+func (p *outboundPluginTransport) AuthName() string {
+	return p.authName
+}
+
+func (p *outboundPluginTransport) ListMessages(ctx context.Context) ([]transport.Object, error) {
+	return p.list(ctx, MethodListMessages)
+}
+
+func (p *outboundPluginTransport) ListAttachments(ctx context.Context) ([]transport.Object, error) {
+	return p.list(ctx, MethodListAttachments)
+}
+
+func (p *outboundPluginTransport) list(ctx context.Context, method string) ([]transport.Object, error) {
+	var objects []Object
+	if err := p.client.call(ctx, method, nil, &objects); err != nil {
+		return nil, err
+	}
+	result := make([]transport.Object, 0, len(objects))
+	for _, obj := range objects {
+		result = append(result, fromWire(obj))
+	}
+	return result, nil
+}
+
+func (p *outboundPluginTransport) Finalize(ctx context.Context, obj transport.Object, err error) error {
+	wire, wireErr := toWire(obj)
+	if wireErr != nil {
+		return wireErr
+	}
+	params := FinalizeParams{
+		Object: wire,
+	}
+	if err != nil {
+		params.Error = err.Error()
+	}
+	return p.client.call(ctx, MethodFinalize, params, nil)
+}
+
+// Close stops the plugin process.
+func (p *outboundPluginTransport) Close() error {
+	return p.client.Close()
+}
+
+type inboundPluginTransport struct {
+	configId string
+	authName string
+	client   *client
+}
+
+// NewInboundTransport returns new InTransport delegating to the configured plugin executable.
+func NewInboundTransport(logger *slog.Logger, configId, authName string, cfg map[string]any) (transport.InboundTransport, error) {
+	client, err := newPluginClient(logger, "inbound", configId, authName, cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &inboundPluginTransport{
+		configId: configId,
+		authName: authName,
+		client:   client,
+	}, nil
+}
+
+func (p *inboundPluginTransport) ConfigId() string {
+	return p.configId
+}
+
+func (p *inboundPluginTransport) AuthName() string {
+	return p.authName
+}
+
+func (p *inboundPluginTransport) ProcessMessage(ctx context.Context, msg transport.Object) (string, error) {
+	wire, err := toWire(msg)
+	if err != nil {
+		return "", err
+	}
+	var result ProcessMessageResult
+	if err := p.client.call(ctx, MethodProcessMessage, wire, &result); err != nil {
+		return "", err
+	}
+	return result.Status, nil
+}
+
+func (p *inboundPluginTransport) ProcessAttachment(ctx context.Context, atc transport.Object) error {
+	wire, err := toWire(atc)
+	if err != nil {
+		return err
+	}
+	return p.client.call(ctx, MethodProcessAttachment, wire, nil)
+}
+
+// HandleAttachment asks the plugin whether it processes the attachment. Failures
+// are logged and treated as a negative answer.
+func (p *inboundPluginTransport) HandleAttachment(url string) bool {
+	var result HandleAttachmentResult
+	if err := p.client.call(context.Background(), MethodHandleAttachment, HandleAttachmentParams{Url: url}, &result); err != nil {
+		p.client.logger.Error("failed to query plugin for attachment", "configId", p.configId, "url", url, "error", err)
+		return false
+	}
+	return result.Handle
+}
+
+// Close stops the plugin process.
+func (p *inboundPluginTransport) Close() error {
+	return p.client.Close()
+}
+
+func newPluginClient(logger *slog.Logger, direction, configId, authName string, cfg map[string]any) (*client, error) {
+	var settings pluginSettings
+	if err := config.Decode(cfg, &settings); err != nil {
+		return nil, fmt.Errorf("failed to decode plugin settings: %w", err)
+	}
+	if configId == "" {
+		return nil, fmt.Errorf("no process id provided")
+	}
+
+	client, err := newClient(logger, settings, InitializeParams{
+		Direction: direction,
+		ConfigId:  configId,
+		AuthName:  authName,
+	})
+	if err != nil {
+		return nil, err
+	}
+	logger.Info("configured plugin process", "direction", direction, "configId", configId, "authName", authName, "command", settings.Command, "args", settings.Args)
+	return client, nil
+}
+
+// toWire converts obj for the plugin protocol, reading streamed content into memory.
+func toWire(obj transport.Object) (Object, error) {
+	content, err := obj.Bytes()
+	if err != nil {
+		return Object{}, fmt.Errorf("failed to read content of %s: %w", obj.Id, err)
+	}
+	return Object{
+		Id:       obj.Id,
+		Content:  content,
+		Metadata: obj.Metadata,
+	}, nil
+}
+
+func fromWire(obj Object) transport.Object {
+	return transport.Object{
+		Id:       obj.Id,
+		Content:  obj.Content,
+		Metadata: obj.Metadata,
+	}
+}
@@ -0,0 +1,60 @@
+package plugin
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+)
+
+// validateOutbound returns the problems of the outbound plugin settings at path.
+func validateOutbound(path string, cfg map[string]any) []config.Problem {
+	return validateSettings(path, cfg)
+}
+
+// validateInbound returns the problems of the inbound plugin settings at path.
+func validateInbound(path string, cfg map[string]any) []config.Problem {
+	return validateSettings(path, cfg)
+}
+
+// validateSettings returns the problems of the plugin settings at path. The
+// settings passed to the plugin are validated by the plugin itself.
+func validateSettings(path string, cfg map[string]any) []config.Problem {
+	problems := config.UnknownFields(path, cfg, pluginSettings{})
+	var settings pluginSettings
+	if err := config.Decode(cfg, &settings); err != nil {
+		return append(problems, config.Problemf(path, "failed to decode plugin settings: %v", err))
+	}
+	if settings.Command == "" {
+		problems = append(problems, config.Problemf(path+".command", "plugin command required"))
+	} else if err := findCommand(settings.Command, settings.Dir); err != nil {
+		problems = append(problems, config.Problemf(path+".command", "%v", err))
+	}
+	if settings.Dir != "" {
+		if info, err := os.Stat(settings.Dir); err != nil {
+			problems = append(problems, config.Problemf(path+".dir", "folder %s is not accessible: %v", settings.Dir, err))
+		} else if !info.IsDir() {
+			problems = append(problems, config.Problemf(path+".dir", "%s is not a folder", settings.Dir))
+		}
+	}
+	problems = append(problems, config.CheckDuration(path+".callTimeout", settings.CallTimeout)...)
+	problems = append(problems, config.CheckDuration(path+".restartBackoff", settings.RestartBackoff)...)
+	problems = append(problems, config.CheckDuration(path+".maxRestartBackoff", settings.MaxRestartBackoff)...)
+	return problems
+}
+
+// findCommand verifies command can be executed. Commands without a path are
+// searched in PATH, relative paths are resolved against dir like exec.Cmd does.
+func findCommand(command, dir string) error {
+	if !strings.ContainsRune(command, '/') && !strings.ContainsRune(command, filepath.Separator) {
+		_, err := exec.LookPath(command)
+		return err
+	}
+	if !filepath.IsAbs(command) && dir != "" {
+		command = filepath.Join(dir, command)
+	}
+	_, err := exec.LookPath(command)
+	return err
+}
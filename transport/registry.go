@@ -0,0 +1,144 @@
+package transport
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+)
+
+// OutboundFactory creates an outbound transport for the given process configuration.
+type OutboundFactory func(logger *slog.Logger, pc config.ProcessConfig, credManager credentials.CredManager) (OutboundTransport, error)
+
+// InboundFactory creates an inbound transport for the given process configuration.
+type InboundFactory func(logger *slog.Logger, pc config.ProcessConfig, credManager credentials.CredManager) (InboundTransport, error)
+
+// Validator returns the problems of the settings of a process found at path,
+// without creating the transport or connecting to remote systems.
+type Validator func(path string, settings map[string]any) []config.Problem
+
+var (
+	registryMu         sync.RWMutex
+	outbounds          = make(map[string]OutboundFactory)
+	inbounds           = make(map[string]InboundFactory)
+	outboundValidators = make(map[string]Validator)
+	inboundValidators  = make(map[string]Validator)
+)
+
+// RegisterOutbound makes an outbound transport available under the given type name.
+// It is intended to be called from the init function of the package implementing
+// the transport and panics if the name is registered twice or factory is nil.
+func RegisterOutbound(name string, factory OutboundFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("transport: register outbound factory is nil")
+	}
+	if _, dup := outbounds[name]; dup {
+		panic("transport: register outbound called twice for type " + name)
+	}
+	outbounds[name] = factory
+}
+
+// RegisterInbound makes an inbound transport available under the given type name.
+// It is intended to be called from the init function of the package implementing
+// the transport and panics if the name is registered twice or factory is nil.
+func RegisterInbound(name string, factory InboundFactory) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	if factory == nil {
+		panic("transport: register inbound factory is nil")
+	}
+	if _, dup := inbounds[name]; dup {
+		panic("transport: register inbound called twice for type " + name)
+	}
+	inbounds[name] = factory
+}
+
+// RegisterOutboundValidator registers the validator of the settings of the
+// outbound transport type name.
+func RegisterOutboundValidator(name string, validator Validator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	outboundValidators[name] = validator
+}
+
+// RegisterInboundValidator registers the validator of the settings of the
+// inbound transport type name.
+func RegisterInboundValidator(name string, validator Validator) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	inboundValidators[name] = validator
+}
+
+// OutboundTypes returns a sorted list of the registered outbound type names.
+func OutboundTypes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return slices.Sorted(maps.Keys(outbounds))
+}
+
+// InboundTypes returns a sorted list of the registered inbound type names.
+func InboundTypes() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	return slices.Sorted(maps.Keys(inbounds))
+}
+
+// NewOutbound creates the outbound transport registered for pc.Type.
+func NewOutbound(logger *slog.Logger, pc config.ProcessConfig, credManager credentials.CredManager) (OutboundTransport, error) {
+	registryMu.RLock()
+	factory, ok := outbounds[pc.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown outbound transport type %q, registered types: %s", pc.Type, strings.Join(OutboundTypes(), ", "))
+	}
+	return factory(logger, pc, credManager)
+}
+
+// NewInbound creates the inbound transport registered for pc.Type.
+func NewInbound(logger *slog.Logger, pc config.ProcessConfig, credManager credentials.CredManager) (InboundTransport, error) {
+	registryMu.RLock()
+	factory, ok := inbounds[pc.Type]
+	registryMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown inbound transport type %q, registered types: %s", pc.Type, strings.Join(InboundTypes(), ", "))
+	}
+	return factory(logger, pc, credManager)
+}
+
+// ValidateOutbound returns the problems of the outbound process pc found at path.
+func ValidateOutbound(path string, pc config.ProcessConfig) []config.Problem {
+	registryMu.RLock()
+	_, ok := outbounds[pc.Type]
+	validator := outboundValidators[pc.Type]
+	registryMu.RUnlock()
+	return validate(path, pc, ok, validator, OutboundTypes)
+}
+
+// ValidateInbound returns the problems of the inbound process pc found at path.
+func ValidateInbound(path string, pc config.ProcessConfig) []config.Problem {
+	registryMu.RLock()
+	_, ok := inbounds[pc.Type]
+	validator := inboundValidators[pc.Type]
+	registryMu.RUnlock()
+	return validate(path, pc, ok, validator, InboundTypes)
+}
+
+func validate(path string, pc config.ProcessConfig, registered bool, validator Validator, types func() []string) []config.Problem {
+	if !registered {
+		if pc.Type == "" {
+			return nil
+		}
+		return []config.Problem{config.Problemf(path+".type", "unknown transport type %q, registered types: %s", pc.Type, strings.Join(types(), ", "))}
+	}
+	if validator == nil {
+		return nil
+	}
+	return validator(path+".settings", pc.Settings)
+}
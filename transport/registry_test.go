@@ -0,0 +1,81 @@
+package transport_test
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+)
+
+type testOutbound struct {
+	pc config.ProcessConfig
+}
+
+func (t *testOutbound) ConfigId() string { return t.pc.Id }
+func (t *testOutbound) AuthName() string { return t.pc.AuthName }
+func (t *testOutbound) ListMessages(ctx context.Context) ([]transport.Object, error) {
+	return nil, nil
+}
+func (t *testOutbound) ListAttachments(ctx context.Context) ([]transport.Object, error) {
+	return nil, nil
+}
+
+func init() {
+	transport.RegisterOutbound("REGISTRY_TEST", func(logger *slog.Logger, pc config.ProcessConfig, credManager credentials.CredManager) (transport.OutboundTransport, error) {
+		return &testOutbound{pc: pc}, nil
+	})
+}
+
+func TestNewOutbound(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	outbound, err := transport.NewOutbound(logger, config.ProcessConfig{
+		Id:       "4711",
+		Type:     "REGISTRY_TEST",
+		AuthName: "auth",
+	}, nil)
+	if err != nil {
+		t.Fatalf("Failed to create registered outbound transport: %v", err)
+	}
+	if outbound.ConfigId() != "4711" {
+		t.Errorf("Expected config id: %s, got: %s", "4711", outbound.ConfigId())
+	}
+	if outbound.AuthName() != "auth" {
+		t.Errorf("Expected auth name: %s, got: %s", "auth", outbound.AuthName())
+	}
+	if !slices.Contains(transport.OutboundTypes(), "REGISTRY_TEST") {
+		t.Errorf("Expected REGISTRY_TEST in registered types, got: %v", transport.OutboundTypes())
+	}
+}
+
+func TestNewUnknownTransport(t *testing.T) {
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	_, err := transport.NewOutbound(logger, config.ProcessConfig{Id: "4711", Type: "FLIE"}, nil)
+	if err == nil {
+		t.Fatal("Expected error for unknown outbound type")
+	}
+	if !strings.Contains(err.Error(), `"FLIE"`) || !strings.Contains(err.Error(), "REGISTRY_TEST") {
+		t.Errorf("Expected error to name type and registered types, got: %v", err)
+	}
+
+	_, err = transport.NewInbound(logger, config.ProcessConfig{Id: "4711", Type: "REGISTRY_TEST"}, nil)
+	if err == nil {
+		t.Fatal("Expected error for type only registered as outbound")
+	}
+}
+
+func TestRegisterDuplicate(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected panic on duplicate registration")
+		}
+	}()
+	transport.RegisterOutbound("REGISTRY_TEST", func(logger *slog.Logger, pc config.ProcessConfig, credManager credentials.CredManager) (transport.OutboundTransport, error) {
+		return nil, nil
+	})
+}
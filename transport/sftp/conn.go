@@ -0,0 +1,215 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+	"github.com/pkg/sftp"
+	"golang.org/x/crypto/ssh"
+)
+
+type connectionSettings struct {
+	Host string `json:"host" yaml:"host"`
+	Port int    `json:"port" yaml:"port"`
+	// CredentialName is resolved through the credential manager. The username
+	// is used for login, the password either for password authentication or
+	// as passphrase of the private key.
+	CredentialName        string `json:"credentialName" yaml:"credentialName"`
+	PrivateKeyFile        string `json:"privateKeyFile" yaml:"privateKeyFile"`
+	HostKey               string `json:"hostKey" yaml:"hostKey"`
+	InsecureIgnoreHostKey bool   `json:"insecureIgnoreHostKey" yaml:"insecureIgnoreHostKey"`
+	Timeout               string `json:"timeout" yaml:"timeout"`
+}
+
+// conn lazily establishes and caches a sftp session. Broken sessions are
+// dropped with reset and reestablished on the next call to client.
+type conn struct {
+	mu          sync.Mutex
+	settings    connectionSettings
+	credManager credentials.CredManager
+	timeout     time.Duration
+	ssh         *ssh.Client
+	sftp        *sftp.Client
+}
+
+func newConn(settings connectionSettings, credManager credentials.CredManager) (*conn, error) {
+	if settings.Host == "" {
+		return nil, fmt.Errorf("no sftp host provided")
+	}
+	if settings.Port == 0 {
+		settings.Port = 22
+	}
+	if settings.Timeout == "" {
+		settings.Timeout = "30s"
+	}
+	timeout, err := time.ParseDuration(settings.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse timeout: %w", err)
+	}
+	if settings.HostKey == "" && !settings.InsecureIgnoreHostKey {
+		return nil, fmt.Errorf("either hostKey or insecureIgnoreHostKey is required")
+	}
+	if credManager == nil {
+		return nil, fmt.Errorf("no credential manager provided")
+	}
+	return &conn{
+		settings:    settings,
+		credManager: credManager,
+		timeout:     timeout,
+	}, nil
+}
+
+func (c *conn) address() string {
+	return net.JoinHostPort(c.settings.Host, strconv.Itoa(c.settings.Port))
+}
+
+func (c *conn) client(ctx context.Context) (*sftp.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sftp != nil {
+		return c.sftp, nil
+	}
+
+	sshConfig, err := c.sshConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := net.Dialer{Timeout: c.timeout}
+	netConn, err := dialer.DialContext(ctx, "tcp", c.address())
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to %s: %w", c.address(), err)
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, c.address(), sshConfig)
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("failed to establish ssh connection to %s: %w", c.address(), err)
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+	sftpClient, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, fmt.Errorf("failed to start sftp session on %s: %w", c.address(), err)
+	}
+
+	c.ssh = sshClient
+	c.sftp = sftpClient
+	return c.sftp, nil
+}
+
+func (c *conn) sshConfig() (*ssh.ClientConfig, error) {
+	auth, err := c.credManager.GetCredential(c.settings.CredentialName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get credential for name: %s: %w", c.settings.CredentialName, err)
+	}
+
+	var authMethod ssh.AuthMethod
+	if c.settings.PrivateKeyFile != "" {
+		pem, err := os.ReadFile(c.settings.PrivateKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read private key: %w", err)
+		}
+		var signer ssh.Signer
+		if auth.Password != "" {
+			signer, err = ssh.ParsePrivateKeyWithPassphrase(pem, []byte(auth.Password))
+		} else {
+			signer, err = ssh.ParsePrivateKey(pem)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse private key: %w", err)
+		}
+		authMethod = ssh.PublicKeys(signer)
+	} else {
+		authMethod = ssh.Password(auth.Password)
+	}
+
+	hostKeyCallback := ssh.InsecureIgnoreHostKey()
+	if !c.settings.InsecureIgnoreHostKey {
+		hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(c.settings.HostKey))
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse host key: %w", err)
+		}
+		hostKeyCallback = ssh.FixedHostKey(hostKey)
+	}
+
+	return &ssh.ClientConfig{
+		User:            auth.Username,
+		Auth:            []ssh.AuthMethod{authMethod},
+		HostKeyCallback: hostKeyCallback,
+		Timeout:         c.timeout,
+	}, nil
+}
+
+// reset closes the current session, the next call to client reconnects.
+func (c *conn) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.sftp != nil {
+		c.sftp.Close()
+		c.sftp = nil
+	}
+	if c.ssh != nil {
+		c.ssh.Close()
+		c.ssh = nil
+	}
+}
+
+// Close closes the underlying connection.
+func (c *conn) Close() error {
+	c.reset()
+	return nil
+}
+
+// do runs fn with a sftp client and drops the session if fn failed because
+// the connection was lost.
+func (c *conn) do(ctx context.Context, fn func(*sftp.Client) error) error {
+	client, err := c.client(ctx)
+	if err != nil {
+		return err
+	}
+	err = fn(client)
+	if err != nil && isConnectionError(err) {
+		c.reset()
+	}
+	return err
+}
+
+// checkFolders verifies all non-empty paths are folders on the server.
+func (c *conn) checkFolders(ctx context.Context, paths ...string) error {
+	return c.do(ctx, func(client *sftp.Client) error {
+		for _, path := range paths {
+			if path == "" {
+				continue
+			}
+			info, err := client.Stat(path)
+			if err != nil {
+				return fmt.Errorf("folder %s is not accessible: %w", path, err)
+			}
+			if !info.IsDir() {
+				return fmt.Errorf("%s is not a folder", path)
+			}
+		}
+		return nil
+	})
+}
+
+// isConnectionError reports whether err is caused by a broken session rather
+// than by a failed file operation on an intact session.
+func isConnectionError(err error) bool {
+	if errors.Is(err, sftp.ErrSSHFxConnectionLost) || errors.Is(err, sftp.ErrSSHFxNoConnection) {
+		return true
+	}
+	var statusErr *sftp.StatusError
+	if errors.As(err, &statusErr) {
+		return false
+	}
+	return !errors.Is(err, fs.ErrNotExist) && !errors.Is(err, fs.ErrPermission) && !errors.Is(err, fs.ErrExist)
+}
@@ -0,0 +1,195 @@
+package sftp
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+	"github.com/pkg/sftp"
+)
+
+type inboundSftpSettings struct {
+	transport.InboundSettings
+	connectionSettings
+	Path           string `json:"path" yaml:"path"`
+	AttachmentPath string `json:"attachmentPath" yaml:"attachmentPath"`
+	Mode           string `json:"mode" yaml:"mode"`
+}
+
+type inboundSftpTransport struct {
+	configId string
+	authName string
+	logger   *slog.Logger
+	settings inboundSftpSettings
+	conn     *conn
+}
+
+// NewInboundTransport returns new InTransport writing messages and attachments into a remote sftp directory.
+func NewInboundTransport(logger *slog.Logger, configId, authName string, cfg map[string]any, credManager credentials.CredManager) (transport.InboundTransport, error) {
+	var settings inboundSftpSettings
+	err := config.Decode(cfg, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode inbound sftp settings: %w", err)
+	}
+
+	if settings.Path == "" {
+		return nil, fmt.Errorf("setting an output folder is required")
+	}
+
+	if settings.Mode == "" {
+		settings.Mode = "create"
+	}
+	if settings.Mode != "create" && settings.Mode != "append" {
+		return nil, fmt.Errorf("unknown mode: %s", settings.Mode)
+	}
+
+	conn, err := newConn(settings.connectionSettings, credManager)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("configured inbound process", "configId", configId, "authName", authName, "host", conn.address(), "folder", settings.Path, "mode", settings.Mode)
+	return &inboundSftpTransport{
+		configId: configId,
+		authName: authName,
+		logger:   logger,
+		settings: settings,
+		conn:     conn,
+	}, nil
+}
+
+func (p *inboundSftpTransport) ConfigId() string {
+	return p.configId
+}
+
+func (p *inboundSftpTransport) AuthName() string {
+	return p.authName
+}
+
+// Close closes the sftp connection.
+func (p *inboundSftpTransport) Close() error {
+	return p.conn.Close()
+}
+
+// CredentialNames returns the credential used to log into the sftp server.
+func (p *inboundSftpTransport) CredentialNames() []string {
+	return []string{p.conn.settings.CredentialName}
+}
+
+// Check verifies the configured remote folders are accessible.
+func (p *inboundSftpTransport) Check(ctx context.Context) error {
+	return p.conn.checkFolders(ctx, p.settings.Path, p.settings.AttachmentPath)
+}
+
+func (p *inboundSftpTransport) HandleAttachment(url string) bool {
+	if p.settings.AttachmentPath == "" || len(p.settings.AttachmentWhitelist) == 0 {
+		return false
+	}
+
+	for _, whitelist := range p.settings.AttachmentWhitelist {
+		if strings.HasPrefix(url, whitelist) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ProcessMessage writes the message into the remote folder.
+func (p *inboundSftpTransport) ProcessMessage(ctx context.Context, msg transport.Object) (string, error) {
+	if p.settings.Mode == "append" {
+		filePath := p.Target(msg, false)
+		p.logger.Info("Appending to remote file", "path", filePath)
+		err := p.conn.do(ctx, func(client *sftp.Client) error {
+			f, err := client.OpenFile(filePath, os.O_APPEND|os.O_WRONLY)
+			if err != nil {
+				return fmt.Errorf("error while open file %s: %w", filePath, err)
+			}
+			defer f.Close()
+			// sftp servers are not required to honor the append flag
+			if _, err := f.Seek(0, io.SeekEnd); err != nil {
+				return fmt.Errorf("error while seeking end of file %s: %w", filePath, err)
+			}
+			if err := writeContent(f, msg); err != nil {
+				return fmt.Errorf("error while writing file %s: %w", filePath, err)
+			}
+			return f.Close()
+		})
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Appending to file: %s", filePath), nil
+	}
+
+	return p.writeObject(ctx, msg, false)
+}
+
+// ProcessAttachment writes the attachment into the remote attachment folder.
+func (p *inboundSftpTransport) ProcessAttachment(ctx context.Context, atc transport.Object) error {
+	_, err := p.writeObject(ctx, atc, true)
+	return err
+}
+
+// Target returns the remote path obj is written to, named after its
+// filename metadata or its id.
+func (p *inboundSftpTransport) Target(obj transport.Object, attachment bool) string {
+	basePath := p.settings.Path
+	if attachment {
+		basePath = p.settings.AttachmentPath
+	}
+	filename := obj.Id
+	if value, ok := obj.Metadata["filename"]; ok && value != "" {
+		filename = value
+	}
+	return path.Join(basePath, filename)
+}
+
+// writeObject uploads obj into a temporary file which gets renamed after completion,
+// so consumers polling the remote folder never pick up partial files.
+func (p *inboundSftpTransport) writeObject(ctx context.Context, obj transport.Object, attachment bool) (string, error) {
+	filePath := p.Target(obj, attachment)
+	basePath, filename := path.Split(filePath)
+	tmpPath := path.Join(basePath, "."+filename+".part")
+
+	p.logger.Info("Creating remote file", "path", filePath)
+	err := p.conn.do(ctx, func(client *sftp.Client) error {
+		f, err := client.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY)
+		if err != nil {
+			return fmt.Errorf("failed to create file %q: %w", tmpPath, err)
+		}
+		defer f.Close()
+		if err := writeContent(f, obj); err != nil {
+			return fmt.Errorf("failed to write to file %q: %w", tmpPath, err)
+		}
+		if err := f.Close(); err != nil {
+			return fmt.Errorf("failed to close file %q: %w", tmpPath, err)
+		}
+		if err := rename(client, tmpPath, filePath); err != nil {
+			return fmt.Errorf("failed to rename %q to %q: %w", tmpPath, filePath, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("Created file: %s", filePath), nil
+}
+
+// writeContent copies the content of obj into w.
+func writeContent(w io.Writer, obj transport.Object) error {
+	content, _, err := obj.Reader()
+	if err != nil {
+		return err
+	}
+	defer content.Close()
+	_, err = io.Copy(w, content)
+	return err
+}
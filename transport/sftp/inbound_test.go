@@ -0,0 +1,131 @@
+package sftp_test
+
+import (
+	"bytes"
+	"context"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/myopenfactory/edi-connector/v2/transport"
+	"github.com/myopenfactory/edi-connector/v2/transport/sftp"
+)
+
+func TestProcessMessage(t *testing.T) {
+	server := startServer(t)
+	inboundDir := t.TempDir()
+	settings := server.settings()
+	settings["path"] = filepath.ToSlash(inboundDir)
+	inbound, err := sftp.NewInboundTransport(discardLogger(), "12345", "", settings, testCredManager{})
+	if err != nil {
+		t.Fatalf("Failed to create inbound transport: %v", err)
+	}
+
+	statusMsg, err := inbound.ProcessMessage(context.TODO(), transport.Object{
+		Id:      "78i7987129878921798",
+		Content: []byte("test"),
+		Metadata: map[string]string{
+			"filename": "inbound.csv",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to process message: %v", err)
+	}
+	if !(strings.HasPrefix(statusMsg, "Created file:") && strings.HasSuffix(statusMsg, "inbound.csv")) {
+		t.Errorf("Unexpected message, got: %v", statusMsg)
+	}
+
+	data, err := os.ReadFile(filepath.Join(inboundDir, "inbound.csv"))
+	if err != nil {
+		t.Fatalf("Could not read test file: %v", err)
+	}
+	expectedData := []byte("test")
+	if !bytes.Equal(data, expectedData) {
+		t.Errorf("Expected data: %s, got: %s", expectedData, data)
+	}
+
+	entries, err := os.ReadDir(inboundDir)
+	if err != nil {
+		t.Fatalf("Failed to list inbound dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("Expected %d inbound entries, got: %d", 1, len(entries))
+	}
+}
+
+func TestProcessMessageAppend(t *testing.T) {
+	server := startServer(t)
+	inboundDir := t.TempDir()
+	settings := server.settings()
+	maps.Copy(settings, map[string]any{
+		"path": filepath.ToSlash(inboundDir),
+		"mode": "append",
+	})
+	inbound, err := sftp.NewInboundTransport(discardLogger(), "12345", "", settings, testCredManager{})
+	if err != nil {
+		t.Fatalf("Failed to create inbound transport: %v", err)
+	}
+
+	filePath := filepath.Join(inboundDir, "inbound.csv")
+	if err := os.WriteFile(filePath, []byte("first line\n"), 0644); err != nil {
+		t.Fatalf("Failed to write existing inbound file: %v", err)
+	}
+
+	_, err = inbound.ProcessMessage(context.TODO(), transport.Object{
+		Id:      "78i7987129878921798",
+		Content: []byte("test"),
+		Metadata: map[string]string{
+			"filename": "inbound.csv",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to process message: %v", err)
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("Could not read test file: %v", err)
+	}
+	expectedData := []byte("first line\ntest")
+	if !bytes.Equal(data, expectedData) {
+		t.Errorf("Expected data: %s, got: %s", expectedData, data)
+	}
+}
+
+func TestProcessAttachment(t *testing.T) {
+	server := startServer(t)
+	attachmentDir := t.TempDir()
+	settings := server.settings()
+	maps.Copy(settings, map[string]any{
+		"path":                filepath.ToSlash(t.TempDir()),
+		"attachmentPath":      filepath.ToSlash(attachmentDir),
+		"attachmentWhitelist": []string{"https://myopenfactory.net/"},
+	})
+	inbound, err := sftp.NewInboundTransport(discardLogger(), "12345", "", settings, testCredManager{})
+	if err != nil {
+		t.Fatalf("Failed to create inbound transport: %v", err)
+	}
+
+	if !inbound.HandleAttachment("https://myopenfactory.net/attachment.pdf") {
+		t.Error("Expected whitelisted attachment to be handled")
+	}
+	if inbound.HandleAttachment("https://example.com/attachment.pdf") {
+		t.Error("Expected foreign attachment to be ignored")
+	}
+
+	err = inbound.ProcessAttachment(context.TODO(), transport.Object{
+		Id:      "1",
+		Content: []byte("attachment"),
+		Metadata: map[string]string{
+			"filename": "attachment.pdf",
+		},
+	})
+	if err != nil {
+		t.Fatalf("Failed to process attachment: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(attachmentDir, "attachment.pdf")); err != nil {
+		t.Errorf("Expected attachment to exist: %v", err)
+	}
+}
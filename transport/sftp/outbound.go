@@ -0,0 +1,320 @@
+package sftp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+	"github.com/pkg/sftp"
+)
+
+type watchSetting struct {
+	Path       string   `json:"path" yaml:"path"`
+	Extensions []string `json:"extensions" yaml:"extensions"`
+	WaitTime   string   `json:"waitTime" yaml:"waitTime"`
+}
+
+type outboundSftpSettings struct {
+	connectionSettings
+	Message     watchSetting `json:"message" yaml:"message"`
+	Attachment  watchSetting `json:"attachment" yaml:"attachment"`
+	ErrorPath   string       `json:"errorPath" yaml:"errorPath"`
+	SuccessPath string       `json:"successPath" yaml:"successPath"`
+	// DuplicatePath receives messages already uploaded within the duplicate
+	// window of the connector, defaults to ErrorPath.
+	DuplicatePath string `json:"duplicatePath" yaml:"duplicatePath"`
+}
+
+type outboundSftpTransport struct {
+	logger   *slog.Logger
+	configId string
+	authName string
+	settings outboundSftpSettings
+	conn     *conn
+
+	// open holds the remote files opened for the content of objects by the
+	// object id until they are closed by the reader or the object is finalized.
+	mu   sync.Mutex
+	open map[*sftp.File]string
+}
+
+func (p *outboundSftpTransport) isMessageEnabled() bool {
+	return p.settings.Message.Path != ""
+}
+
+func (p *outboundSftpTransport) isAttachmentEnabled() bool {
+	return p.settings.Attachment.Path != ""
+}
+
+// NewOutboundTransport returns new OutTransport reading messages and attachments from a remote sftp directory.
+func NewOutboundTransport(logger *slog.Logger, configId, authName string, cfg map[string]any, credManager credentials.CredManager) (transport.OutboundTransport, error) {
+	var settings outboundSftpSettings
+	err := config.Decode(cfg, &settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode outbound sftp settings: %w", err)
+	}
+	if settings.Message.WaitTime == "" {
+		settings.Message.WaitTime = "15s"
+	}
+	if settings.Attachment.WaitTime == "" {
+		settings.Attachment.WaitTime = "15s"
+	}
+
+	if configId == "" {
+		return nil, fmt.Errorf("no process id provided")
+	}
+
+	conn, err := newConn(settings.connectionSettings, credManager)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &outboundSftpTransport{
+		logger:   logger,
+		settings: settings,
+		configId: configId,
+		authName: authName,
+		conn:     conn,
+		open:     make(map[*sftp.File]string),
+	}
+
+	if p.isMessageEnabled() {
+		if settings.ErrorPath == "" {
+			return nil, fmt.Errorf("error folder is required")
+		}
+		p.logger.Info("configured outbound process", "configId", p.configId, "authName", p.authName, "host", conn.address(), "successFolder", settings.SuccessPath, "errorFolder", settings.ErrorPath, "duplicateFolder", settings.DuplicatePath)
+		message := settings.Message
+		p.logger.Info("watching remote folder for messages", "folder", message.Path, "extensions", message.Extensions, "waitTime", message.WaitTime)
+	} else {
+		p.logger.Info("message polling disabled")
+	}
+
+	if p.isAttachmentEnabled() {
+		attachment := settings.Attachment
+		p.logger.Info("watching remote folder for attachments", "folder", attachment.Path, "extensions", attachment.Extensions, "waitTime", attachment.WaitTime)
+	} else {
+		p.logger.Info("attachment polling disabled")
+	}
+
+	return p, nil
+}
+
+func (p *outboundSftpTransport) ConfigId() string {
+	return p.configId
+}
+
+func (p *outboundSftpTransport) AuthName() string {
+	return p.authName
+}
+
+// CredentialNames returns the credential used to log into the sftp server.
+func (p *outboundSftpTransport) CredentialNames() []string {
+	return []string{p.conn.settings.CredentialName}
+}
+
+// Close closes the remote files still open and the sftp connection.
+func (p *outboundSftpTransport) Close() error {
+	p.mu.Lock()
+	for f := range p.open {
+		f.Close()
+	}
+	clear(p.open)
+	p.mu.Unlock()
+	return p.conn.Close()
+}
+
+// Check verifies the configured remote folders are accessible.
+func (p *outboundSftpTransport) Check(ctx context.Context) error {
+	return p.conn.checkFolders(ctx, p.settings.Message.Path, p.settings.Attachment.Path, p.settings.ErrorPath, p.settings.SuccessPath, p.settings.DuplicatePath)
+}
+
+// ListMessages lists all messages found within the remote message folder. Each file gets
+// serialized into an transport.Object streaming its content.
+func (p *outboundSftpTransport) ListMessages(ctx context.Context) ([]transport.Object, error) {
+	if !p.isMessageEnabled() {
+		return []transport.Object{}, nil
+	}
+	return p.list(ctx, p.settings.Message)
+}
+
+// ListAttachments lists all attachments found within the remote attachment folder. Each file gets
+// serialized into an transport.Object streaming its content.
+func (p *outboundSftpTransport) ListAttachments(ctx context.Context) ([]transport.Object, error) {
+	if !p.isAttachmentEnabled() {
+		return []transport.Object{}, nil
+	}
+	return p.list(ctx, p.settings.Attachment)
+}
+
+func (p *outboundSftpTransport) list(ctx context.Context, watch watchSetting) ([]transport.Object, error) {
+	duration, err := time.ParseDuration(watch.WaitTime)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse duration: %w", err)
+	}
+
+	objects := make([]transport.Object, 0)
+	err = p.conn.do(ctx, func(client *sftp.Client) error {
+		fileInfos, err := listFilesLastModifiedBefore(client, watch.Path, time.Now().Add(-duration))
+		if err != nil {
+			return fmt.Errorf("failed to list files within %s: %w", watch.Path, err)
+		}
+		p.logger.Debug("searched remote folder for files", "folder", watch.Path, "count", len(fileInfos))
+
+		for _, fileInfo := range fileInfos {
+			fileExtension := path.Ext(fileInfo.Name())
+			if fileExtension != "" {
+				fileExtension = fileExtension[1:]
+			}
+			if !slices.Contains(watch.Extensions, fileExtension) {
+				continue
+			}
+			objects = append(objects, p.fileObject(path.Join(watch.Path, fileInfo.Name()), fileInfo))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return objects, nil
+}
+
+// fileObject returns an object streaming the content of the remote file at
+// filePath. Files not closed by the reader are closed by Finalize.
+func (p *outboundSftpTransport) fileObject(filePath string, info os.FileInfo) transport.Object {
+	return transport.Object{
+		Id:   filePath,
+		Size: info.Size(),
+		Open: func() (io.ReadCloser, error) {
+			var f *sftp.File
+			// the listing context may be gone by the time the content is read,
+			// connecting is bounded by the connection timeout instead
+			err := p.conn.do(context.Background(), func(client *sftp.Client) error {
+				var err error
+				f, err = client.Open(filePath)
+				return err
+			})
+			if err != nil {
+				return nil, fmt.Errorf("error while opening %s: %w", filePath, err)
+			}
+			p.mu.Lock()
+			p.open[f] = filePath
+			p.mu.Unlock()
+			return &remoteFile{File: f, transport: p}, nil
+		},
+	}
+}
+
+// remoteFile is a remote file opened for the content of an object, closing it
+// stops tracking it within the transport.
+type remoteFile struct {
+	*sftp.File
+	transport *outboundSftpTransport
+}
+
+func (f *remoteFile) Close() error {
+	f.transport.mu.Lock()
+	delete(f.transport.open, f.File)
+	f.transport.mu.Unlock()
+	return f.File.Close()
+}
+
+// closeFiles closes the remote files opened for id and not yet closed by the reader.
+func (p *outboundSftpTransport) closeFiles(id string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for f, fileId := range p.open {
+		if fileId == id {
+			f.Close()
+			delete(p.open, f)
+		}
+	}
+}
+
+func (p *outboundSftpTransport) Finalize(ctx context.Context, obj transport.Object, err error) error {
+	file := obj.Id
+	// some servers refuse to move or delete files still open
+	p.closeFiles(file)
+	if errors.Is(err, transport.ErrDuplicate) && p.settings.DuplicatePath != "" {
+		destination := path.Join(p.settings.DuplicatePath, path.Base(file))
+		return p.conn.do(ctx, func(client *sftp.Client) error {
+			return rename(client, file, destination)
+		})
+	}
+	if err != nil {
+		destination := path.Join(p.settings.ErrorPath, path.Base(file))
+		return p.conn.do(ctx, func(client *sftp.Client) error {
+			return rename(client, file, destination)
+		})
+	}
+
+	if p.settings.SuccessPath != "" {
+		newfile := path.Join(p.settings.SuccessPath, path.Base(file))
+		err := p.conn.do(ctx, func(client *sftp.Client) error {
+			return rename(client, file, newfile)
+		})
+		if err != nil {
+			return fmt.Errorf("error while moving file %s: %w", file, err)
+		}
+		p.logger.Info("remote file moved", "source", file, "destination", newfile)
+		return nil
+	}
+
+	err = p.conn.do(ctx, func(client *sftp.Client) error {
+		return client.Remove(file)
+	})
+	if err != nil {
+		return fmt.Errorf("error while deleting file %s: %w", file, err)
+	}
+	p.logger.Info("remote file deleted", "path", file)
+
+	return nil
+}
+
+// listFilesLastModifiedBefore lists all regular files within dir last modified before t
+// ordered by modification time.
+func listFilesLastModifiedBefore(client *sftp.Client, dir string, t time.Time) ([]os.FileInfo, error) {
+	entries, err := client.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read directory %s: %w", dir, err)
+	}
+
+	files := []os.FileInfo{}
+	for _, entry := range entries {
+		if !entry.Mode().IsRegular() {
+			continue
+		}
+		if entry.ModTime().Before(t) {
+			files = append(files, entry)
+		}
+	}
+
+	slices.SortFunc(files, func(a os.FileInfo, b os.FileInfo) int {
+		return a.ModTime().Compare(b.ModTime())
+	})
+
+	return files, nil
+}
+
+// rename moves src to dst replacing an existing dst. The posix-rename extension
+// is used when the server supports it as plain sftp rename fails on existing targets.
+func rename(client *sftp.Client, src, dst string) error {
+	if _, ok := client.HasExtension("posix-rename@openssh.com"); ok {
+		return client.PosixRename(src, dst)
+	}
+	if _, err := client.Stat(dst); err == nil {
+		if err := client.Remove(dst); err != nil {
+			return err
+		}
+	}
+	return client.Rename(src, dst)
+}
@@ -0,0 +1,204 @@
+package sftp_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/myopenfactory/edi-connector/v2/transport"
+	"github.com/myopenfactory/edi-connector/v2/transport/sftp"
+)
+
+func TestListMessages(t *testing.T) {
+	server := startServer(t)
+	outboundDir := t.TempDir()
+	// sftp reports modification times with second precision only
+	waitTime := time.Minute
+	now := time.Now()
+	files := []struct {
+		name    string
+		modTime time.Time
+	}{
+		{name: "outbound.txt", modTime: now.Add(-3 * time.Hour)},
+		{name: "outbound.test", modTime: now.Add(-3 * time.Hour)},
+		{name: "outbound.csv", modTime: now.Add(-2 * time.Hour)},
+		{name: "outbound_new.txt", modTime: now},
+	}
+	for _, file := range files {
+		filePath := filepath.Join(outboundDir, file.name)
+		if err := os.WriteFile(filePath, []byte(strings.ReplaceAll(file.name, ".", "_")), 0644); err != nil {
+			t.Fatalf("Failed to create outbound file: %v", err)
+		}
+		if err := os.Chtimes(filePath, file.modTime, file.modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+	}
+
+	settings := server.settings()
+	maps.Copy(settings, map[string]any{
+		"message": map[string]any{
+			"path":       filepath.ToSlash(outboundDir),
+			"extensions": []string{"txt", "csv"},
+			"waitTime":   waitTime.String(),
+		},
+		"errorPath": filepath.ToSlash(t.TempDir()),
+	})
+	outbound, err := sftp.NewOutboundTransport(discardLogger(), "12345", "", settings, testCredManager{})
+	if err != nil {
+		t.Fatalf("Failed to create outbound transport: %v", err)
+	}
+	messages, err := outbound.ListMessages(context.TODO())
+	if err != nil {
+		t.Fatalf("Failed to list messages: %v", err)
+	}
+	expectedLength := 2
+	if len(messages) != expectedLength {
+		t.Fatalf("Expected %d messages, got: %d", expectedLength, len(messages))
+	}
+
+	expectedContent := []byte("outbound_txt")
+	if !bytes.Equal(content(t, messages[0]), expectedContent) {
+		t.Errorf("Expected content %s, got: %s", expectedContent, content(t, messages[0]))
+	}
+	expectedContent = []byte("outbound_csv")
+	if !bytes.Equal(content(t, messages[1]), expectedContent) {
+		t.Errorf("Expected content %s, got: %s", expectedContent, content(t, messages[1]))
+	}
+}
+
+func TestListAttachments(t *testing.T) {
+	server := startServer(t)
+	attachmentDir := t.TempDir()
+	waitTime := time.Minute
+	modTime := time.Now().Add(-time.Hour)
+	for _, name := range []string{"attachment.pdf", "attachment.ignore"} {
+		filePath := filepath.Join(attachmentDir, name)
+		if err := os.WriteFile(filePath, []byte(strings.ReplaceAll(name, ".", "_")), 0644); err != nil {
+			t.Fatalf("Failed to create attachment file: %v", err)
+		}
+		if err := os.Chtimes(filePath, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+	}
+
+	settings := server.settings()
+	maps.Copy(settings, map[string]any{
+		"attachment": map[string]any{
+			"path":       filepath.ToSlash(attachmentDir),
+			"extensions": []string{"pdf"},
+			"waitTime":   waitTime.String(),
+		},
+	})
+	outbound, err := sftp.NewOutboundTransport(discardLogger(), "12345", "", settings, testCredManager{})
+	if err != nil {
+		t.Fatalf("Failed to create outbound transport: %v", err)
+	}
+	attachments, err := outbound.ListAttachments(context.TODO())
+	if err != nil {
+		t.Fatalf("Failed to list attachments: %v", err)
+	}
+	if len(attachments) != 1 {
+		t.Fatalf("Expected %d attachments, got: %d", 1, len(attachments))
+	}
+	expectedContent := []byte("attachment_pdf")
+	if !bytes.Equal(content(t, attachments[0]), expectedContent) {
+		t.Errorf("Expected content %s, got: %s", expectedContent, content(t, attachments[0]))
+	}
+}
+
+func TestFinalize(t *testing.T) {
+	server := startServer(t)
+	outboundDir := t.TempDir()
+	successDir := t.TempDir()
+	errorDir := t.TempDir()
+
+	settings := server.settings()
+	maps.Copy(settings, map[string]any{
+		"message": map[string]any{
+			"path":       filepath.ToSlash(outboundDir),
+			"extensions": []string{"txt"},
+		},
+		"errorPath":   filepath.ToSlash(errorDir),
+		"successPath": filepath.ToSlash(successDir),
+	})
+	outbound, err := sftp.NewOutboundTransport(discardLogger(), "12345", "", settings, testCredManager{})
+	if err != nil {
+		t.Fatalf("Failed to create outbound transport: %v", err)
+	}
+	finalizer, ok := outbound.(transport.Finalizer)
+	if !ok {
+		t.Fatal("Expected finalizer")
+	}
+
+	tests := []struct {
+		name   string
+		err    error
+		target string
+	}{
+		{name: "success.txt", target: successDir},
+		{name: "error.txt", err: fmt.Errorf("fake error"), target: errorDir},
+	}
+	for _, test := range tests {
+		outboundFilepath := filepath.Join(outboundDir, test.name)
+		if err := os.WriteFile(outboundFilepath, []byte(test.name), 0644); err != nil {
+			t.Fatalf("Failed to create outbound file: %v", err)
+		}
+		modTime := time.Now().Add(-time.Hour)
+		if err := os.Chtimes(outboundFilepath, modTime, modTime); err != nil {
+			t.Fatalf("Failed to set modification time: %v", err)
+		}
+		messages, err := outbound.ListMessages(context.TODO())
+		if err != nil || len(messages) != 1 {
+			t.Fatalf("Failed to list %s: %v", test.name, err)
+		}
+		// the content is still being read when the object is finalized
+		if _, _, err := messages[0].Reader(); err != nil {
+			t.Fatalf("Failed to open %s: %v", test.name, err)
+		}
+		err = finalizer.Finalize(context.TODO(), messages[0], test.err)
+		if err != nil {
+			t.Fatalf("Failed to finalize %s: %v", test.name, err)
+		}
+		if _, err := os.Stat(outboundFilepath); err == nil {
+			t.Errorf("Expected file %s to not exist but still found it", test.name)
+		}
+		if _, err := os.Stat(filepath.Join(test.target, test.name)); os.IsNotExist(err) {
+			t.Errorf("Expected file %s to be moved into %s but did not find it", test.name, test.target)
+		}
+	}
+}
+
+func content(t *testing.T, obj transport.Object) []byte {
+	t.Helper()
+	data, err := obj.Bytes()
+	if err != nil {
+		t.Fatalf("Failed to read content of %s: %v", obj.Id, err)
+	}
+	return data
+}
+
+func TestWrongHostKey(t *testing.T) {
+	server := startServer(t)
+	other := startServer(t)
+
+	settings := server.settings()
+	settings["hostKey"] = other.hostKey
+	settings["message"] = map[string]any{
+		"path":       filepath.ToSlash(t.TempDir()),
+		"extensions": []string{"txt"},
+	}
+	settings["errorPath"] = filepath.ToSlash(t.TempDir())
+	outbound, err := sftp.NewOutboundTransport(discardLogger(), "12345", "", settings, testCredManager{})
+	if err != nil {
+		t.Fatalf("Failed to create outbound transport: %v", err)
+	}
+	if _, err := outbound.ListMessages(context.TODO()); err == nil {
+		t.Error("Expected host key mismatch error, got none")
+	}
+}
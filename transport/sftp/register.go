@@ -0,0 +1,20 @@
+package sftp
+
+import (
+	"log/slog"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"github.com/myopenfactory/edi-connector/v2/credentials"
+	"github.com/myopenfactory/edi-connector/v2/transport"
+)
+
+func init() {
+	transport.RegisterOutbound("SFTP", func(logger *slog.Logger, pc config.ProcessConfig, credManager credentials.CredManager) (transport.OutboundTransport, error) {
+		return NewOutboundTransport(logger, pc.Id, pc.AuthName, pc.Settings, credManager)
+	})
+	transport.RegisterInbound("SFTP", func(logger *slog.Logger, pc config.ProcessConfig, credManager credentials.CredManager) (transport.InboundTransport, error) {
+		return NewInboundTransport(logger, pc.Id, pc.AuthName, pc.Settings, credManager)
+	})
+	transport.RegisterOutboundValidator("SFTP", validateOutbound)
+	transport.RegisterInboundValidator("SFTP", validateInbound)
+}
@@ -0,0 +1,69 @@
+package sftp
+
+import (
+	"os"
+
+	"github.com/myopenfactory/edi-connector/v2/config"
+	"golang.org/x/crypto/ssh"
+)
+
+// validateConnection returns the problems of the connection settings at path.
+// The remote folders aren't checked, as validation doesn't connect to the server.
+func validateConnection(path string, settings connectionSettings) []config.Problem {
+	var problems []config.Problem
+	if settings.Host == "" {
+		problems = append(problems, config.Problemf(path+".host", "sftp host required"))
+	}
+	if settings.Port < 0 || settings.Port > 65535 {
+		problems = append(problems, config.Problemf(path+".port", "port %d out of range", settings.Port))
+	}
+	problems = append(problems, config.CheckDuration(path+".timeout", settings.Timeout)...)
+	if settings.HostKey == "" && !settings.InsecureIgnoreHostKey {
+		problems = append(problems, config.Problemf(path+".hostKey", "either hostKey or insecureIgnoreHostKey is required"))
+	} else if settings.HostKey != "" && !settings.InsecureIgnoreHostKey {
+		if _, _, _, _, err := ssh.ParseAuthorizedKey([]byte(settings.HostKey)); err != nil {
+			problems = append(problems, config.Problemf(path+".hostKey", "failed to parse host key: %v", err))
+		}
+	}
+	if settings.PrivateKeyFile != "" {
+		if _, err := os.ReadFile(settings.PrivateKeyFile); err != nil {
+			problems = append(problems, config.Problemf(path+".privateKeyFile", "failed to read private key: %v", err))
+		}
+	}
+	return problems
+}
+
+// validateOutbound returns the problems of the outbound sftp settings at path.
+func validateOutbound(path string, cfg map[string]any) []config.Problem {
+	problems := config.UnknownFields(path, cfg, outboundSftpSettings{})
+	var settings outboundSftpSettings
+	if err := config.Decode(cfg, &settings); err != nil {
+		return append(problems, config.Problemf(path, "failed to decode outbound sftp settings: %v", err))
+	}
+	problems = append(problems, validateConnection(path, settings.connectionSettings)...)
+	problems = append(problems, config.CheckDuration(path+".message.waitTime", settings.Message.WaitTime)...)
+	problems = append(problems, config.CheckDuration(path+".attachment.waitTime", settings.Attachment.WaitTime)...)
+	if settings.Message.Path != "" && settings.ErrorPath == "" {
+		problems = append(problems, config.Problemf(path+".errorPath", "folder required"))
+	}
+	return problems
+}
+
+// validateInbound returns the problems of the inbound sftp settings at path.
+func validateInbound(path string, cfg map[string]any) []config.Problem {
+	problems := config.UnknownFields(path, cfg, inboundSftpSettings{})
+	var settings inboundSftpSettings
+	if err := config.Decode(cfg, &settings); err != nil {
+		return append(problems, config.Problemf(path, "failed to decode inbound sftp settings: %v", err))
+	}
+	problems = append(problems, validateConnection(path, settings.connectionSettings)...)
+	if settings.Path == "" {
+		problems = append(problems, config.Problemf(path+".path", "folder required"))
+	}
+	switch settings.Mode {
+	case "", "create", "append":
+	default:
+		problems = append(problems, config.Problemf(path+".mode", "unknown mode %q, expected create or append", settings.Mode))
+	}
+	return problems
+}
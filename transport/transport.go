@@ -1,9 +1,17 @@
 package transport
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"io"
+	"iter"
+	"time"
 )
 
+// ErrDuplicate is passed to Finalize for objects whose content was already uploaded.
+var ErrDuplicate = errors.New("duplicate content")
+
 type InboundSettings struct {
 	AttachmentWhitelist []string `json:"attachmentWhitelist" yaml:"attachmentWhitelist"`
 }
@@ -12,6 +20,46 @@ type Object struct {
 	Id       string
 	Content  []byte
 	Metadata map[string]string
+	// Open streams the content instead of holding it in Content, every call
+	// returns a new reader starting at the beginning.
+	Open func() (io.ReadCloser, error)
+	// Size is the length of the content provided by Open.
+	Size int64
+	// Path is the local file holding the content of a streamed object.
+	// Transports may move the file instead of copying its content.
+	Path string
+}
+
+// Reader returns the content of o as stream together with its size.
+func (o Object) Reader() (io.ReadCloser, int64, error) {
+	if o.Open == nil {
+		return nopCloser{bytes.NewReader(o.Content)}, int64(len(o.Content)), nil
+	}
+	r, err := o.Open()
+	if err != nil {
+		return nil, 0, err
+	}
+	return r, o.Size, nil
+}
+
+// nopCloser keeps the reader seekable unlike io.NopCloser.
+type nopCloser struct {
+	*bytes.Reader
+}
+
+func (nopCloser) Close() error { return nil }
+
+// Bytes returns the whole content of o, reading a streamed object into memory.
+func (o Object) Bytes() ([]byte, error) {
+	if o.Open == nil {
+		return o.Content, nil
+	}
+	r, err := o.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
 }
 
 type ConfigInfo interface {
@@ -33,6 +81,65 @@ type InboundTransport interface {
 	HandleAttachment(url string) bool
 }
 
+// Lister is implemented by outbound transports able to list objects lazily.
+// Objects are yielded one at a time and their content is loaded on demand,
+// the listing stops as soon as the consumer stops iterating.
+type Lister interface {
+	Messages(ctx context.Context) iter.Seq2[Object, error]
+	Attachments(ctx context.Context) iter.Seq2[Object, error]
+}
+
+// FailedObject is an object kept by a transport after it failed to be processed.
+type FailedObject struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Requeuer is implemented by outbound transports keeping failed objects in an
+// error folder, allowing them to be processed again.
+type Requeuer interface {
+	// Failed lists the objects within the error folder.
+	Failed(ctx context.Context) ([]FailedObject, error)
+	// Requeue moves the failed object name back to be picked up with the next run.
+	Requeue(ctx context.Context, name string) error
+}
+
+// Checker is implemented by transports able to verify the folders or
+// servers they deliver to and pick up from are accessible.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+// CredentialUser is implemented by transports resolving credentials of their
+// own through the credential manager, e.g. to log into a server.
+type CredentialUser interface {
+	// CredentialNames returns the names of the credentials used by the transport.
+	CredentialNames() []string
+}
+
+// Notifier is implemented by outbound transports able to report new objects
+// before the next run is due.
+type Notifier interface {
+	// Notify returns a channel receiving a value whenever new objects are
+	// ready to be listed. A nil channel is returned if notifications are disabled.
+	Notify() <-chan struct{}
+}
+
+// Stager is implemented by inbound transports providing a folder for content
+// received before it is delivered. Placed next to the destination, delivering
+// a streamed object is a rename instead of a copy. An empty folder selects the
+// temporary directory of the system.
+type Stager interface {
+	StagingDir(attachment bool) string
+}
+
+// Targeter is implemented by inbound transports able to report the
+// destination an object would be delivered to, e.g. for dry runs.
+type Targeter interface {
+	Target(obj Object, attachment bool) string
+}
+
 type Finalizer interface {
 	Finalize(context.Context, Object, error) error
 }